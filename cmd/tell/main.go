@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -14,9 +15,21 @@ import (
 	"tell/internal/db"
 	httpx "tell/internal/http"
 	"tell/internal/jobs"
+	"tell/internal/memo"
+	"tell/internal/webpush"
 )
 
 func main() {
+	// needs no config or database
+	if len(os.Args) > 1 && os.Args[1] == "vapid-keys" {
+		pub, priv, err := webpush.GenerateVAPIDKeys()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("VAPID_PRIVATE_KEY=%s\n# public key (derived): %s\n", priv, pub)
+		return
+	}
+
 	cfg, _ := config.Load()
 
 	gdb, err := db.Connect(cfg.DatabaseURL)
@@ -27,15 +40,73 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			runReplay(gdb, os.Args[2:])
+		case "snapshots":
+			runSnapshotBackfill(gdb, os.Args[2:])
+		default:
+			log.Fatalf("unknown command: %s", os.Args[1])
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	hub := memo.NewEventHub(cfg.DatabaseURL)
+	go hub.Run(ctx)
+
+	notifiers := jobs.NewNotifiers()
+	notifiers.Register("log", jobs.LogNotifier{}, true)
+	notifiers.RegisterRequired("inapp", &jobs.InboxNotifier{DB: gdb})
+	if cfg.SMTPAddr != "" {
+		if _, err := jobs.ParseAddress(cfg.SMTPFrom); err != nil {
+			log.Fatalf("SMTP_FROM: %v", err)
+		}
+		notifiers.Register("email", &jobs.EmailNotifier{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.SMTPFrom,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			DB:       gdb,
+		}, false)
+	}
+	if cfg.VAPIDPrivateKey != "" {
+		vapid, err := webpush.NewVAPID(cfg.VAPIDPrivateKey, cfg.VAPIDSubject)
+		if err != nil {
+			log.Fatal(err)
+		}
+		notifiers.Register("push", &jobs.PushNotifier{
+			DB:     gdb,
+			Client: &webpush.Client{VAPID: vapid},
+		}, true)
+	}
+
 	jwtSvc := auth.NewJWT(cfg.JWTSecret)
-	r := httpx.NewRouter(cfg, gdb, jwtSvc)
+	r := httpx.NewRouter(cfg, gdb, jwtSvc, hub, notifiers)
 
 	// worker
 	jobsRepo := &jobs.Repo{DB: gdb}
-	worker := &jobs.Worker{ID: "worker-1", Repo: jobsRepo, DB: gdb}
+	memoSvc := &memo.Service{DB: gdb, SnapshotEvery: cfg.SnapshotEvery}
+	host, _ := os.Hostname()
+	worker := &jobs.Worker{
+		ID:          fmt.Sprintf("%s-%d", host, os.Getpid()),
+		Repo:        jobsRepo,
+		DB:          gdb,
+		Snapshots:   memoSvc,
+		Notifiers:   notifiers,
+		Reminders:   memoSvc,
+		Concurrency: cfg.WorkerConcurrency,
+		BatchSize:   cfg.WorkerBatchSize,
+		DSN:         cfg.DatabaseURL,
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	go worker.Run(ctx)
+	workerDone := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(workerDone)
+	}()
 
 	srv := &http.Server{
 		Addr:              cfg.HTTPAddr,
@@ -59,4 +130,5 @@ func main() {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
 	_ = srv.Shutdown(shutdownCtx)
+	<-workerDone
 }
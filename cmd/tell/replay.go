@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strings"
+
+	"tell/internal/memo"
+
+	"gorm.io/gorm"
+)
+
+// runReplay rebuilds memo_projections from memo_events.
+//
+//	tell replay [-user ID] [-dry-run] [-shadow] [-from-snapshots]
+func runReplay(gdb *gorm.DB, args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	userID := fs.Uint64("user", 0, "only replay memos of this user (0 = all)")
+	dryRun := fs.Bool("dry-run", false, "report diffs against current rows, write nothing")
+	shadow := fs.Bool("shadow", false, "rebuild into a shadow table and swap atomically")
+	fromSnap := fs.Bool("from-snapshots", false, "start from the latest snapshot of each memo instead of CREATED")
+	batch := fs.Int("batch", 200, "memos per batch")
+	_ = fs.Parse(args)
+
+	r := &memo.Replayer{DB: gdb, BatchSize: *batch}
+	rep, err := r.Replay(context.Background(), memo.ReplayOptions{
+		UserID: *userID,
+		DryRun: *dryRun,
+		Shadow: *shadow,
+
+		FromSnapshots: *fromSnap,
+	})
+	if err != nil {
+		log.Fatalf("replay failed: %v", err)
+	}
+
+	for _, d := range rep.Diffs {
+		log.Printf("diff memo=%d user=%d fields=%s\n", d.MemoID, d.UserID, strings.Join(d.Fields, ","))
+	}
+	log.Printf("replay done: memos=%d events=%d diffs=%d dry_run=%t shadow=%t\n",
+		rep.Memos, rep.Events, len(rep.Diffs), *dryRun, *shadow)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"tell/internal/jobs"
+	"tell/internal/memo"
+
+	"gorm.io/gorm"
+)
+
+// runSnapshotBackfill enqueues SNAPSHOT_BACKFILL jobs, one per user; the
+// worker does the folding.
+//
+//	tell snapshots [-user ID]
+func runSnapshotBackfill(gdb *gorm.DB, args []string) {
+	fs := flag.NewFlagSet("snapshots", flag.ExitOnError)
+	userID := fs.Uint64("user", 0, "only this user (0 = all)")
+	_ = fs.Parse(args)
+
+	var users []uint64
+	if *userID != 0 {
+		users = []uint64{*userID}
+	} else if err := gdb.Model(&memo.Memo{}).Distinct("user_id").Pluck("user_id", &users).Error; err != nil {
+		log.Fatalf("list users failed: %v", err)
+	}
+
+	repo := &jobs.Repo{DB: gdb}
+	for _, uid := range users {
+		if err := repo.EnqueueSnapshotBackfill(uid, 0); err != nil {
+			log.Fatalf("enqueue failed (user=%d): %v", uid, err)
+		}
+	}
+	log.Printf("enqueued snapshot backfill for %d users\n", len(users))
+}
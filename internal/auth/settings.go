@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserSettings holds per-user preferences. A missing row means the
+// defaults: UTC and no quiet hours.
+type UserSettings struct {
+	UserID   uint64 `gorm:"primaryKey"`
+	Timezone string `gorm:"type:text;not null;default:'UTC'"` // IANA name
+
+	// Quiet hours as "HH:MM" wall-clock times in Timezone; the window may
+	// cross midnight (22:00-07:00). Both nil = no quiet hours.
+	QuietStart *string `gorm:"type:text"`
+	QuietEnd   *string `gorm:"type:text"`
+
+	UpdatedAt time.Time `gorm:"not null;default:now()"`
+}
+
+func (UserSettings) TableName() string { return "user_settings" }
+
+// LoadSettings returns the user's settings or the defaults.
+func LoadSettings(db *gorm.DB, userID uint64) (UserSettings, error) {
+	s := UserSettings{UserID: userID, Timezone: "UTC"}
+	err := db.Where("user_id = ?", userID).First(&s).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return s, err
+	}
+	return s, nil
+}
+
+// Location falls back to UTC for an unknown zone.
+func (s UserSettings) Location() *time.Location {
+	if loc, err := time.LoadLocation(s.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// ParseClock parses "HH:MM" into minutes after midnight.
+func ParseClock(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (HH:MM)", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// QuietUntil reports whether t falls inside quiet hours and, if so, when
+// the window ends.
+func (s UserSettings) QuietUntil(t time.Time) (time.Time, bool) {
+	if s.QuietStart == nil || s.QuietEnd == nil {
+		return time.Time{}, false
+	}
+	start, err1 := ParseClock(*s.QuietStart)
+	end, err2 := ParseClock(*s.QuietEnd)
+	if err1 != nil || err2 != nil || start == end {
+		return time.Time{}, false
+	}
+
+	local := t.In(s.Location())
+	now := local.Hour()*60 + local.Minute()
+	y, m, d := local.Date()
+	endOn := func(day int) time.Time {
+		return time.Date(y, m, day, end/60, end%60, 0, 0, local.Location())
+	}
+
+	if start < end {
+		if now >= start && now < end {
+			return endOn(d), true
+		}
+		return time.Time{}, false
+	}
+	// window crosses midnight
+	switch {
+	case now >= start:
+		return endOn(d + 1), true
+	case now < end:
+		return endOn(d), true
+	}
+	return time.Time{}, false
+}
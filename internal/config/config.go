@@ -2,8 +2,11 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
 
+	"tell/internal/webpush"
+
 	"github.com/joho/godotenv"
 )
 
@@ -14,6 +17,26 @@ type Config struct {
 	CORSAllowCredentials bool
 
 	JWTSecret string
+
+	// SnapshotEvery writes a memo snapshot every N events.
+	SnapshotEvery int
+
+	// WorkerConcurrency jobs run at once per process; WorkerBatchSize are
+	// claimed per round trip (0 = WorkerConcurrency).
+	WorkerConcurrency int
+	WorkerBatchSize   int
+
+	// SMTP for the email reminder channel; disabled when SMTPAddr is empty.
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// VAPID key pair for Web Push; the push channel is off when
+	// VAPIDPrivateKey is empty. VAPIDPublicKey is derived from it.
+	VAPIDPrivateKey string
+	VAPIDPublicKey  string
+	VAPIDSubject    string
 }
 
 func Load() (Config, error) {
@@ -34,6 +57,40 @@ func Load() (Config, error) {
 	}
 
 	cfg.JWTSecret = mustGetenv("JWT_SECRET")
+
+	n, err := strconv.Atoi(getenv("SNAPSHOT_EVERY", "100"))
+	if err != nil || n <= 0 {
+		panic("invalid env: SNAPSHOT_EVERY")
+	}
+	cfg.SnapshotEvery = n
+
+	n, err = strconv.Atoi(getenv("WORKER_CONCURRENCY", "4"))
+	if err != nil || n <= 0 {
+		panic("invalid env: WORKER_CONCURRENCY")
+	}
+	cfg.WorkerConcurrency = n
+
+	n, err = strconv.Atoi(getenv("WORKER_BATCH_SIZE", "0"))
+	if err != nil || n < 0 {
+		panic("invalid env: WORKER_BATCH_SIZE")
+	}
+	cfg.WorkerBatchSize = n
+
+	cfg.SMTPAddr = getenv("SMTP_ADDR", "")
+	cfg.SMTPFrom = getenv("SMTP_FROM", "tell@localhost")
+	cfg.SMTPUsername = getenv("SMTP_USERNAME", "")
+	cfg.SMTPPassword = getenv("SMTP_PASSWORD", "")
+
+	cfg.VAPIDPrivateKey = getenv("VAPID_PRIVATE_KEY", "")
+	cfg.VAPIDSubject = getenv("VAPID_SUBJECT", "mailto:tell@localhost")
+	if cfg.VAPIDPrivateKey != "" {
+		v, err := webpush.NewVAPID(cfg.VAPIDPrivateKey, cfg.VAPIDSubject)
+		if err != nil {
+			panic("invalid env: VAPID_PRIVATE_KEY")
+		}
+		cfg.VAPIDPublicKey = v.PublicKey
+	}
+
 	return cfg, nil
 }
 
@@ -4,8 +4,11 @@ import (
 	"fmt"
 
 	"tell/internal/auth"
+	"tell/internal/ical"
 	"tell/internal/jobs"
 	"tell/internal/memo"
+	"tell/internal/webhook"
+	"tell/internal/webpush"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -20,19 +23,38 @@ func Connect(dsn string) (*gorm.DB, error) {
 }
 
 func AutoMigrateAndIndexes(gdb *gorm.DB) error {
+	// memo_reminders is new: seed it from the projections' remind_at
+	seedReminders := !gdb.Migrator().HasTable(&memo.MemoReminder{}) && gdb.Migrator().HasTable(&memo.MemoProjection{})
+
 	// Tables
 	if err := gdb.AutoMigrate(
 		&memo.Memo{},
 		&memo.MemoEvent{},
 		&memo.MemoProjection{},
+		&memo.MemoReminder{},
 		&memo.Tag{},
 		&memo.MemoTag{},
+		&memo.MemoSnapshot{},
 		&jobs.Job{},
+		&jobs.NotificationPref{},
+		&jobs.ChannelDelivery{},
+		&jobs.Notification{},
+		&webhook.Subscription{},
+		&webhook.Delivery{},
+		&webpush.Subscription{},
+		&ical.Feed{},
 		&auth.User{},
+		&auth.UserSettings{},
 	); err != nil {
 		return err
 	}
 
+	if seedReminders {
+		if err := seedMemoReminders(gdb); err != nil {
+			return fmt.Errorf("seed memo_reminders: %w", err)
+		}
+	}
+
 	// Constraints / unique (user_id, name) for tags
 	if err := gdb.Exec(`create unique index if not exists uq_tags_user_name on tags(user_id, name);`).Error; err != nil {
 		return err
@@ -67,9 +89,13 @@ where idempotency_key is not null;
 	stmts := []string{
 		`create index if not exists idx_events_memo on memo_events(memo_id, id);`,
 		`create index if not exists idx_events_user_created on memo_events(user_id, created_at desc);`,
+		`create index if not exists idx_events_user_id on memo_events(user_id, id);`,
+		`create unique index if not exists uq_snapshots_memo_rev_version on memo_snapshots(memo_id, rev, version);`,
 		`create index if not exists idx_proj_user_updated on memo_projections(user_id, updated_at desc);`,
 		`create index if not exists idx_jobs_due on jobs(status, run_at);`,
 		`create index if not exists idx_jobs_lock on jobs(status, locked_at);`,
+		`create index if not exists idx_notifications_unread on notifications(user_id) where read_at is null;`,
+		`create index if not exists idx_reminders_user_due on memo_reminders(user_id, remind_at) where remind_at is not null;`,
 	}
 	for _, s := range stmts {
 		if err := gdb.Exec(s).Error; err != nil {
@@ -79,3 +105,23 @@ where idempotency_key is not null;
 
 	return nil
 }
+
+// seedMemoReminders upgrades a database from before memo_reminders, whose
+// projections had only remind_at: each one set becomes the pending
+// memo.DefaultReminderID reminder, as a replay of its events would fold it.
+func seedMemoReminders(gdb *gorm.DB) error {
+	return gdb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+insert into memo_reminders (memo_id, reminder_id, user_id, remind_at, status)
+select memo_id, ?, user_id, remind_at, 'PENDING'
+from memo_projections
+where remind_at is not null
+`, memo.DefaultReminderID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+update memo_projections set reminder_status = 'PENDING'
+where remind_at is not null and reminder_status = ''
+`).Error
+	})
+}
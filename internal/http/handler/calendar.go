@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tell/internal/auth"
+	"tell/internal/ical"
+	"tell/internal/memo"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CalendarHandler serves the iCalendar feed of pending reminders behind a
+// secret URL token, and its rotation.
+type CalendarHandler struct {
+	DB *gorm.DB
+}
+
+type calendarFeedDTO struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// Rotate: POST /me/calendar-feed. Issues a new feed URL; the previous one
+// stops working. The token is only shown here.
+func (h *CalendarHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	token, hash, err := ical.NewToken()
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	feed := ical.Feed{UserID: uid, TokenHash: hash, CreatedAt: time.Now()}
+	if err := h.DB.WithContext(r.Context()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]any{"token_hash": hash, "created_at": feed.CreatedAt, "last_used_at": nil}),
+	}).Create(&feed).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(calendarFeedDTO{
+		Token: token,
+		URL:   fmt.Sprintf("%s://%s/calendar/%s.ics", scheme, r.Host, token),
+	})
+}
+
+// Revoke: DELETE /me/calendar-feed
+func (h *CalendarHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	res := h.DB.WithContext(r.Context()).Where("user_id = ?", uid).Delete(&ical.Feed{})
+	if res.Error != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Feed: GET /calendar/{token}.ics (no auth; the token is the credential).
+// One VEVENT per pending reminder, recurring ones with their RRULE. A
+// snoozed run of a recurring reminder gets a VEVENT of its own, since the
+// series does not have it.
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	db := h.DB.WithContext(r.Context())
+
+	var feed ical.Feed
+	if err := db.Where("token_hash = ?", ical.HashToken(chi.URLParam(r, "token"))).First(&feed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := auth.LoadSettings(db, feed.UserID)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	rows, err := memo.PendingReminders(db, feed.UserID)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	cal := ical.Calendar{Name: "Tell", Loc: settings.Location()}
+	for _, row := range rows {
+		ev := ical.Event{
+			UID:         fmt.Sprintf("memo-%d-%s@tell", row.MemoID, row.ReminderID),
+			Start:       *row.RemindAt,
+			Summary:     memoSummary(row.Content),
+			Description: row.Content,
+			Categories:  row.Tags,
+			Stamp:       row.UpdatedAt,
+		}
+		if row.Rule != "" && row.Start != nil {
+			if row.Status == "SNOOZED" {
+				snoozed := ev
+				snoozed.UID = fmt.Sprintf("memo-%d-%s-snoozed@tell", row.MemoID, row.ReminderID)
+				cal.Events = append(cal.Events, snoozed)
+			}
+			ev.Start, ev.Rule = *row.Start, row.Rule
+		}
+		cal.Events = append(cal.Events, ev)
+	}
+
+	now := time.Now()
+	_ = db.Model(&ical.Feed{}).Where("user_id = ?", feed.UserID).Update("last_used_at", now).Error
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	_ = ical.Write(w, cal)
+}
+
+// memoSummary is the first line of content, shortened for a calendar title.
+func memoSummary(content string) string {
+	s, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "Reminder"
+	}
+	if utf8.RuneCountInString(s) > 80 {
+		s = string([]rune(s)[:80]) + "…"
+	}
+	return s
+}
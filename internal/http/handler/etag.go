@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETags are the projection version (last event id), quoted.
+func etag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// parseETag accepts `"12"`, `W/"12"` and a bare `12`.
+func parseETag(s string) (uint64, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "W/")
+	s = strings.Trim(s, `"`)
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// parseETagList reads an If-Match / If-None-Match value: a comma separated
+// list of tags, or "*" (star is true). Quoted tags that are not versions
+// are dropped, as no memo can match them; ok is false for anything else
+// that is not a tag.
+func parseETagList(s string) (versions []uint64, star bool, ok bool) {
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+			continue
+		case part == "*":
+			star = true
+			continue
+		}
+		v, ok := parseETag(part)
+		if !ok {
+			if strings.HasSuffix(part, `"`) && strings.HasPrefix(strings.TrimPrefix(part, "W/"), `"`) {
+				continue
+			}
+			return nil, false, false
+		}
+		versions = append(versions, v)
+	}
+	return versions, star, true
+}
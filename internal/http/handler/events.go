@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tell/internal/auth"
+	"tell/internal/memo"
+
+	"gorm.io/gorm"
+)
+
+// EventFeedHandler serves every memo event of the user in id order, so
+// clients with a local cache can catch up from a cursor.
+type EventFeedHandler struct {
+	DB  *gorm.DB
+	Hub *memo.EventHub // wakes long-polls on commit; nil = poll only
+}
+
+type eventFeedDTO struct {
+	Events     []memoEventDTO `json:"events"`
+	NextCursor uint64         `json:"next_cursor"`
+}
+
+const (
+	maxFeedWait   = 30 * time.Second
+	feedPollEvery = 5 * time.Second // fallback when a notification is lost
+)
+
+// List: GET /events?after=<event_id>&limit=&wait=<seconds>
+// With wait > 0 an empty page is held until events arrive or wait expires.
+func (h *EventFeedHandler) List(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var after uint64
+	if v := strings.TrimSpace(r.URL.Query().Get("after")); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid after", http.StatusBadRequest)
+			return
+		}
+		after = n
+	}
+
+	limit := 100
+	if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
+			limit = n
+		}
+	}
+
+	var wait time.Duration
+	if v := strings.TrimSpace(r.URL.Query().Get("wait")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid wait", http.StatusBadRequest)
+			return
+		}
+		wait = min(time.Duration(n)*time.Second, maxFeedWait)
+	}
+	deadline := time.Now().Add(wait)
+
+	var wake <-chan struct{}
+	if wait > 0 && h.Hub != nil {
+		ch, cancel := h.Hub.Subscribe(uid)
+		defer cancel()
+		wake = ch
+	}
+
+	var evs []memo.MemoEvent
+	for {
+		var err error
+		if evs, err = memo.EventsAfter(h.DB.WithContext(r.Context()), uid, after, limit); err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		if len(evs) > 0 || !time.Now().Before(deadline) {
+			break
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+		case <-time.After(min(feedPollEvery, time.Until(deadline))):
+		}
+	}
+
+	out, err := toMemoEventDTOs(evs)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	next := after
+	if n := len(evs); n > 0 {
+		next = evs[n-1].ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(eventFeedDTO{Events: out, NextCursor: next})
+}
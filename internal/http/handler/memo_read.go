@@ -2,7 +2,9 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -15,18 +17,73 @@ import (
 )
 
 type MemoReadHandler struct {
-	DB *gorm.DB
+	DB  *gorm.DB
+	Svc *memo.Service
 }
 
 type memoDTO struct {
-	MemoID    uint64     `json:"memo_id"`
-	UserID    uint64     `json:"user_id"`
-	Content   string     `json:"content"`
-	Archived  bool       `json:"archived"`
-	RemindAt  *time.Time `json:"remind_at"`
-	Tags      []string   `json:"tags"`
-	Version   uint64     `json:"version"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	MemoID         uint64        `json:"memo_id"`
+	UserID         uint64        `json:"user_id"`
+	Content        string        `json:"content"`
+	Archived       bool          `json:"archived"`
+	RemindAt       *time.Time    `json:"remind_at"`
+	ReminderStatus string        `json:"reminder_status"`
+	LastFiredAt    *time.Time    `json:"last_fired_at"`
+	Reminders      []reminderDTO `json:"reminders,omitempty"` // single-memo reads only
+	Tags           []string      `json:"tags"`
+	Version        uint64        `json:"version"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+func toMemoDTO(p memo.MemoProjection) memoDTO {
+	tags := []string(p.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return memoDTO{
+		MemoID:         p.MemoID,
+		UserID:         p.UserID,
+		Content:        p.Content,
+		Archived:       p.Archived,
+		RemindAt:       p.RemindAt,
+		ReminderStatus: p.ReminderStatus,
+		LastFiredAt:    p.LastFiredAt,
+		Reminders:      toReminderDTOs(p.Reminders),
+		Tags:           tags,
+		Version:        p.Version,
+		UpdatedAt:      p.UpdatedAt,
+	}
+}
+
+type reminderDTO struct {
+	ID          string     `json:"id"`
+	RemindAt    *time.Time `json:"remind_at"`
+	Status      string     `json:"status"`
+	Rule        string     `json:"rule,omitempty"`
+	Start       *time.Time `json:"start,omitempty"`
+	FiredCount  int        `json:"fired_count"`
+	SnoozeCount int        `json:"snooze_count"`
+	LastFiredAt *time.Time `json:"last_fired_at"`
+}
+
+func toReminderDTOs(rs []memo.MemoReminder) []reminderDTO {
+	if len(rs) == 0 {
+		return nil
+	}
+	out := make([]reminderDTO, 0, len(rs))
+	for _, r := range rs {
+		out = append(out, reminderDTO{
+			ID:          r.ReminderID,
+			RemindAt:    r.RemindAt,
+			Status:      r.Status,
+			Rule:        r.Rule,
+			Start:       r.Start,
+			FiredCount:  r.FiredCount,
+			SnoozeCount: r.SnoozeCount,
+			LastFiredAt: r.LastFiredAt,
+		})
+	}
+	return out
 }
 
 type memoEventDTO struct {
@@ -35,10 +92,32 @@ type memoEventDTO struct {
 	UserID         uint64          `json:"user_id"`
 	Type           string          `json:"type"`
 	Payload        json.RawMessage `json:"payload"`
+	SchemaVersion  int             `json:"schema_version"`
 	IdempotencyKey *string         `json:"idempotency_key"`
 	CreatedAt      time.Time       `json:"created_at"`
 }
 
+// toMemoEventDTOs serves old payload shapes in the current schema.
+func toMemoEventDTOs(evs []memo.MemoEvent) ([]memoEventDTO, error) {
+	out := make([]memoEventDTO, 0, len(evs))
+	for _, e := range evs {
+		if err := memo.Upcast(&e); err != nil {
+			return nil, err
+		}
+		out = append(out, memoEventDTO{
+			ID:             e.ID,
+			MemoID:         e.MemoID,
+			UserID:         e.UserID,
+			Type:           e.Type,
+			Payload:        e.Payload,
+			SchemaVersion:  e.SchemaVersion,
+			IdempotencyKey: e.IdempotencyKey,
+			CreatedAt:      e.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
 func (h *MemoReadHandler) List(w http.ResponseWriter, r *http.Request) {
 	uid, _ := auth.UserIDFromContext(r.Context())
 
@@ -73,22 +152,68 @@ func (h *MemoReadHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	out := make([]memoDTO, 0, len(rows))
 	for _, p := range rows {
-		out = append(out, memoDTO{
-			MemoID:    p.MemoID,
-			UserID:    p.UserID,
-			Content:   p.Content,
-			Archived:  p.Archived,
-			RemindAt:  p.RemindAt,
-			Tags:      []string(p.Tags),
-			Version:   p.Version,
-			UpdatedAt: p.UpdatedAt,
-		})
+		out = append(out, toMemoDTO(p))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(out)
 }
 
+func (h *MemoReadHandler) Get(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	idStr := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	// point-in-time read: fold events instead of reading the projection
+	if v := strings.TrimSpace(r.URL.Query().Get("as_of")); v != "" {
+		at, err := memo.ParseAsOf(v)
+		if err != nil {
+			http.Error(w, "invalid as_of (event id or RFC3339)", http.StatusBadRequest)
+			return
+		}
+		p, err := h.Svc.StateAt(r.Context(), uid, id64, at)
+		if err != nil {
+			if errors.Is(err, memo.ErrNotFound) {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(toMemoDTO(p))
+		return
+	}
+
+	var p memo.MemoProjection
+	if err := h.DB.Where("memo_id=? AND user_id=?", id64, uid).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag(p.Version))
+	if versions, star, ok := parseETagList(r.Header.Get("If-None-Match")); ok && (star || slices.Contains(versions, p.Version)) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if err := memo.LoadReminders(h.DB.WithContext(r.Context()), &p); err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(toMemoDTO(p))
+}
+
 func (h *MemoReadHandler) Timeline(w http.ResponseWriter, r *http.Request) {
 	uid, _ := auth.UserIDFromContext(r.Context())
 
@@ -112,17 +237,15 @@ func (h *MemoReadHandler) Timeline(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	out := make([]memoEventDTO, 0, len(evs))
-	for _, e := range evs {
-		out = append(out, memoEventDTO{
-			ID:             e.ID,
-			MemoID:         e.MemoID,
-			UserID:         e.UserID,
-			Type:           e.Type,
-			Payload:        e.Payload,
-			IdempotencyKey: e.IdempotencyKey,
-			CreatedAt:      e.CreatedAt,
-		})
+	out, err := toMemoEventDTOs(evs)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	// last event id is the projection version
+	if n := len(evs); n > 0 {
+		w.Header().Set("ETag", etag(evs[n-1].ID))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -166,3 +289,87 @@ func (h *MemoReadHandler) Tags(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(out)
 }
+
+type diffOpDTO struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type memoDiffDTO struct {
+	From        uint64            `json:"from"`
+	To          uint64            `json:"to"`
+	Unified     string            `json:"unified"`
+	Words       []diffOpDTO       `json:"words"`
+	TagsAdded   []string          `json:"tags_added"`
+	TagsRemoved []string          `json:"tags_removed"`
+	Archived    *memo.BoolChange  `json:"archived,omitempty"`
+	RemindAt    *memo.TimeChange  `json:"remind_at,omitempty"`
+	Reminders   []reminderDiffDTO `json:"reminders,omitempty"`
+}
+
+type reminderDiffDTO struct {
+	ID   string       `json:"id"`
+	From *reminderDTO `json:"from"` // null when added
+	To   *reminderDTO `json:"to"`   // null when removed
+}
+
+func toReminderDiffDTO(c memo.ReminderChange) reminderDiffDTO {
+	one := func(r *memo.MemoReminder) *reminderDTO {
+		if r == nil {
+			return nil
+		}
+		return &toReminderDTOs([]memo.MemoReminder{*r})[0]
+	}
+	return reminderDiffDTO{ID: c.ReminderID, From: one(c.From), To: one(c.To)}
+}
+
+func (h *MemoReadHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	idStr := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	from, err1 := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+	to, err2 := strconv.ParseUint(r.URL.Query().Get("to"), 10, 64)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "from and to (event ids) required", http.StatusBadRequest)
+		return
+	}
+
+	d, err := h.Svc.Diff(r.Context(), uid, id64, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, memo.ErrNotFound):
+			http.Error(w, "not found", http.StatusNotFound)
+		case errors.Is(err, memo.ErrInvalidRange):
+			http.Error(w, "from must be <= to", http.StatusBadRequest)
+		default:
+			http.Error(w, "server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	out := memoDiffDTO{
+		From:        d.From,
+		To:          d.To,
+		Unified:     d.Unified,
+		Words:       make([]diffOpDTO, 0, len(d.Words)),
+		TagsAdded:   append([]string{}, d.TagsAdded...),
+		TagsRemoved: append([]string{}, d.TagsRemoved...),
+		Archived:    d.Archived,
+		RemindAt:    d.RemindAt,
+	}
+	for _, op := range d.Words {
+		out.Words = append(out.Words, diffOpDTO{Op: op.Op, Text: op.Text})
+	}
+	for _, c := range d.Reminders {
+		out.Reminders = append(out.Reminders, toReminderDiffDTO(c))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
@@ -2,14 +2,16 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"tell/internal/auth"
-	"tell/internal/jobs"
 	"tell/internal/memo"
+	"tell/internal/rrule"
 
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
@@ -23,6 +25,16 @@ type MemoHandler struct {
 type createMemoReq struct {
 	Content  string  `json:"content"`
 	RemindAt *string `json:"remind_at"` // RFC3339 optional
+	RRule    *string `json:"rrule"`     // optional, makes remind_at the start of a series
+
+	// DetectReminder sets a reminder from a date/time phrase in content
+	// ("besok jam 9") when remind_at is not given.
+	DetectReminder bool `json:"detect_reminder"`
+}
+
+type detectedDTO struct {
+	Phrase   string    `json:"phrase"`
+	RemindAt time.Time `json:"remind_at"`
 }
 
 func (h *MemoHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -41,13 +53,29 @@ func (h *MemoHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	var remindAt *time.Time
 	if req.RemindAt != nil && strings.TrimSpace(*req.RemindAt) != "" {
-		t, err := time.Parse(time.RFC3339, *req.RemindAt)
+		t, err := parseRemindAt(h.DB.WithContext(r.Context()), uid, *req.RemindAt)
 		if err != nil {
-			http.Error(w, "invalid remind_at (RFC3339)", http.StatusBadRequest)
+			http.Error(w, "invalid remind_at (RFC3339 or local 2006-01-02T15:04)", http.StatusBadRequest)
 			return
 		}
 		remindAt = &t
 	}
+	rule, ok := parseRRule(w, req.RRule, remindAt)
+	if !ok {
+		return
+	}
+
+	var detected *detectedDTO
+	if req.DetectReminder && remindAt == nil {
+		d, err := h.detectReminder(r, uid, req.Content)
+		if err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		if d != nil {
+			detected, remindAt = d, &d.RemindAt
+		}
+	}
 
 	var idem *string
 	if k := strings.TrimSpace(r.Header.Get("Idempotency-Key")); k != "" {
@@ -55,9 +83,10 @@ func (h *MemoHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	id, err := h.Svc.CreateMemo(r.Context(), uid, memo.CreateMemoInput{
-		Content:  req.Content,
-		RemindAt: remindAt,
-		IdemKey:  idem,
+		Content:    req.Content,
+		RemindAt:   remindAt,
+		RemindRule: rule,
+		IdemKey:    idem,
 	})
 	if err != nil {
 		http.Error(w, "server error", http.StatusInternalServerError)
@@ -66,13 +95,24 @@ func (h *MemoHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(map[string]any{"id": id})
+	resp := map[string]any{"id": id}
+	if req.DetectReminder {
+		resp["detected"] = detected
+	}
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 type appendEventReq struct {
-	Type     string  `json:"type"`
-	Content  *string `json:"content"`
-	RemindAt *string `json:"remind_at"`
+	Type            string  `json:"type"`
+	Content         *string `json:"content"`
+	RemindAt        *string `json:"remind_at"`
+	RRule           *string `json:"rrule"`           // REMINDER_SET
+	Snooze          string  `json:"snooze"`          // REMINDER_SNOOZED: "10m", "1h", "tomorrow_morning"
+	TZ              string  `json:"tz"`              // IANA zone for snooze presets, default the user's settings timezone
+	DetectReminder  bool    `json:"detect_reminder"` // UPDATED: set a reminder from content
+	ReminderID      string  `json:"reminder_id"`     // REMINDER_*: which reminder, default "default"
+	ToEventID       *uint64 `json:"to_event_id"`     // REVERTED
+	ExpectedVersion *uint64 `json:"expected_version"`
 }
 
 func (h *MemoHandler) AppendEvent(w http.ResponseWriter, r *http.Request) {
@@ -94,90 +134,190 @@ func (h *MemoHandler) AppendEvent(w http.ResponseWriter, r *http.Request) {
 
 	var remindAt *time.Time
 	if req.RemindAt != nil && strings.TrimSpace(*req.RemindAt) != "" {
-		t, err := time.Parse(time.RFC3339, *req.RemindAt)
+		t, err := parseRemindAt(h.DB.WithContext(r.Context()), uid, *req.RemindAt)
 		if err != nil {
-			http.Error(w, "invalid remind_at (RFC3339)", http.StatusBadRequest)
+			http.Error(w, "invalid remind_at (RFC3339 or local 2006-01-02T15:04)", http.StatusBadRequest)
 			return
 		}
 		remindAt = &t
 	}
+	rule, ok := parseRRule(w, req.RRule, remindAt)
+	if !ok {
+		return
+	}
+	loc, ok := parseTZ(w, req.TZ)
+	if !ok {
+		return
+	}
+
+	var detected *detectedDTO
+	if req.DetectReminder && req.Type == "UPDATED" && req.Content != nil && remindAt == nil {
+		d, err := h.detectReminder(r, uid, *req.Content)
+		if err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		if d != nil {
+			detected, remindAt = d, &d.RemindAt
+		}
+	}
 
 	var idem *string
 	if k := strings.TrimSpace(r.Header.Get("Idempotency-Key")); k != "" {
 		idem = &k
 	}
 
-	// 1) apply domain event first
-	err = h.Svc.AppendEvent(r.Context(), memo.AppendEventInput{
-		MemoID:   id64,
-		UserID:   uid,
-		Type:     req.Type,
-		Content:  req.Content,
-		RemindAt: remindAt,
-		IdemKey:  idem,
-	})
-
-	// 2) handle errors BEFORE any job side-effect
-	if err != nil {
-		switch err {
-		case memo.ErrNotFound:
-			http.Error(w, "not found", http.StatusNotFound)
-			return
-		case memo.ErrInvalidEvent:
-			http.Error(w, "invalid event", http.StatusBadRequest)
-			return
-		default:
-			http.Error(w, "server error", http.StatusInternalServerError)
+	// expected version: If-Match header wins (412 on conflict), body field gives 409.
+	// If-Match may list several versions; "*" only needs the memo to exist.
+	expected := req.ExpectedVersion
+	var expectedAny []uint64
+	fromHeader := false
+	if im := strings.TrimSpace(r.Header.Get("If-Match")); im != "" {
+		versions, star, ok := parseETagList(im)
+		if !ok {
+			http.Error(w, "invalid If-Match", http.StatusBadRequest)
 			return
 		}
+		if !star {
+			if len(versions) == 0 {
+				http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+				return
+			}
+			if expected != nil && !slices.Contains(versions, *expected) {
+				http.Error(w, "If-Match and expected_version disagree", http.StatusBadRequest)
+				return
+			}
+			if expected == nil {
+				expectedAny = versions
+			}
+			fromHeader = true
+		}
 	}
 
-	// 3) job side-effects (DB required)
-	if (req.Type == "REMINDER_SET" && remindAt != nil) || req.Type == "REMINDER_CLEARED" {
-		if h.DB == nil {
-			http.Error(w, "server misconfigured (db)", http.StatusInternalServerError)
-			return
-		}
+	// reminder jobs are reconciled by the service in the same tx
+	version, err := h.Svc.AppendEvent(r.Context(), memo.AppendEventInput{
+		MemoID:           id64,
+		UserID:           uid,
+		Type:             req.Type,
+		Content:          req.Content,
+		RemindAt:         remindAt,
+		DetectedPhrase:   detectedPhrase(detected),
+		RemindRule:       rule,
+		ReminderID:       strings.TrimSpace(req.ReminderID),
+		Snooze:           req.Snooze,
+		Location:         loc,
+		IdemKey:          idem,
+		ToEventID:        req.ToEventID,
+		ExpectedVersion:  expected,
+		ExpectedVersions: expectedAny,
+	})
+	if err != nil {
+		writeAppendError(w, err, fromHeader)
+		return
 	}
 
-	// 3a) REMINDER_SET: dedupe pending reminder jobs for this memo, then enqueue
-	if req.Type == "REMINDER_SET" && remindAt != nil {
-		// delete any pending reminder job for this memo (avoid double dispatch)
-		_ = h.DB.Exec(`
-			delete from jobs
-			where user_id = ?
-			  and type = 'REMINDER_DISPATCH'
-			  and status = 'PENDING'
-			  and (payload->>'memo_id')::bigint = ?
-		`, uid, id64).Error
-
-		payload, _ := json.Marshal(map[string]any{"memo_id": id64})
-		j := jobs.Job{
-			UserID:  uid,
-			Type:    "REMINDER_DISPATCH",
-			Payload: payload,
-			RunAt:   *remindAt,
-			Status:  "PENDING",
-		}
-		if err := h.DB.Create(&j).Error; err != nil {
-			http.Error(w, "failed enqueue job", http.StatusInternalServerError)
-			return
+	w.Header().Set("ETag", etag(version))
+	if req.DetectReminder {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"version": version, "detected": detected})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAppendError maps an AppendEvent error to its response. A version
+// conflict is 412 when the expected version came from If-Match.
+func writeAppendError(w http.ResponseWriter, err error, fromHeader bool) {
+	var conflict *memo.ConflictError
+	var stateErr *memo.StateError
+	switch {
+	case errors.Is(err, memo.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, memo.ErrInvalidEvent):
+		http.Error(w, "invalid event", http.StatusBadRequest)
+	case errors.As(err, &conflict):
+		w.Header().Set("ETag", etag(conflict.Current))
+		if fromHeader {
+			http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+		} else {
+			http.Error(w, "version conflict", http.StatusConflict)
 		}
+	case errors.As(err, &stateErr):
+		writeError(w, http.StatusConflict, stateErr.Code, stateErr.Msg)
+	default:
+		http.Error(w, "server error", http.StatusInternalServerError)
 	}
+}
 
-	// 3b) REMINDER_CLEARED: cancel pending reminder jobs
-	if req.Type == "REMINDER_CLEARED" {
-		if err := h.DB.Exec(`
-			delete from jobs
-			where user_id = ?
-			  and type = 'REMINDER_DISPATCH'
-			  and status = 'PENDING'
-			  and (payload->>'memo_id')::bigint = ?
-		`, uid, id64).Error; err != nil {
-			http.Error(w, "failed cancel reminder jobs", http.StatusInternalServerError)
-			return
+func detectedPhrase(d *detectedDTO) string {
+	if d == nil {
+		return ""
+	}
+	return d.Phrase
+}
+
+// detectReminder looks for a date/time phrase in content, resolved in the
+// user's timezone. nil when there is none.
+func (h *MemoHandler) detectReminder(r *http.Request, uid uint64, content string) (*detectedDTO, error) {
+	s, err := auth.LoadSettings(h.DB.WithContext(r.Context()), uid)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := memo.ExtractReminder(content, time.Now(), s.Location())
+	if !ok {
+		return nil, nil
+	}
+	return &detectedDTO{Phrase: d.Phrase, RemindAt: d.At}, nil
+}
+
+// parseRemindAt takes an RFC3339 instant, or a local date-time without
+// offset that is read in the user's timezone.
+func parseRemindAt(db *gorm.DB, uid uint64, v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	s, err := auth.LoadSettings(db, uid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var perr error
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		var t time.Time
+		if t, perr = time.ParseInLocation(layout, v, s.Location()); perr == nil {
+			return t, nil
 		}
 	}
+	return time.Time{}, perr
+}
 
-	w.WriteHeader(http.StatusNoContent)
+// parseTZ loads an optional IANA zone name.
+func parseTZ(w http.ResponseWriter, name string) (*time.Location, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		http.Error(w, "invalid tz", http.StatusBadRequest)
+		return nil, false
+	}
+	return loc, true
+}
+
+// parseRRule validates an optional rrule field; it needs remind_at as its start.
+func parseRRule(w http.ResponseWriter, s *string, remindAt *time.Time) (string, bool) {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return "", true
+	}
+	if remindAt == nil {
+		http.Error(w, "rrule requires remind_at", http.StatusBadRequest)
+		return "", false
+	}
+	rule, err := rrule.Parse(*s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return rule.String(), true
 }
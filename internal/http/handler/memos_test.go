@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tell/internal/memo"
+)
+
+func TestWriteAppendError(t *testing.T) {
+	for _, se := range []*memo.StateError{
+		memo.ErrAlreadyArchived, memo.ErrNotArchived, memo.ErrMemoArchived,
+		memo.ErrNoReminder, memo.ErrNotFired, memo.ErrTooManyReminders,
+	} {
+		rec := httptest.NewRecorder()
+		writeAppendError(rec, fmt.Errorf("append: %w", se), false)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("%s: status %d", se.Code, rec.Code)
+		}
+		var body struct{ Code, Error string }
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Code != se.Code {
+			t.Errorf("%s: body %+v (%v)", se.Code, body, err)
+		}
+	}
+
+	tests := []struct {
+		err        error
+		fromHeader bool
+		want       int
+	}{
+		{memo.ErrNotFound, false, http.StatusNotFound},
+		{memo.ErrInvalidEvent, false, http.StatusBadRequest},
+		{&memo.ConflictError{Expected: 3, Current: 5}, false, http.StatusConflict},
+		{&memo.ConflictError{Expected: 3, Current: 5}, true, http.StatusPreconditionFailed},
+		{fmt.Errorf("db down"), false, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeAppendError(rec, tt.err, tt.fromHeader)
+		if rec.Code != tt.want {
+			t.Errorf("%v (If-Match %v): status %d, want %d", tt.err, tt.fromHeader, rec.Code, tt.want)
+		}
+	}
+}
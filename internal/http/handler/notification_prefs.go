@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"tell/internal/auth"
+	"tell/internal/jobs"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationPrefsHandler lets users pick their reminder channels.
+type NotificationPrefsHandler struct {
+	DB       *gorm.DB
+	Channels *jobs.Notifiers
+}
+
+type channelPrefDTO struct {
+	Channel  string `json:"channel"`
+	Enabled  bool   `json:"enabled"`
+	Required bool   `json:"required,omitempty"` // cannot be turned off
+}
+
+func (h *NotificationPrefsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	enabled, err := h.Channels.Enabled(h.DB, uid)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	on := map[string]bool{}
+	for _, c := range enabled {
+		on[c] = true
+	}
+
+	out := []channelPrefDTO{}
+	for _, c := range h.Channels.Names() {
+		out = append(out, channelPrefDTO{Channel: c, Enabled: on[c], Required: h.Channels.Required(c)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Put takes {"email": true, "log": false}; channels not listed keep their setting.
+func (h *NotificationPrefsHandler) Put(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var req map[string]bool
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+
+	known := map[string]bool{}
+	for _, c := range h.Channels.Names() {
+		known[c] = true
+	}
+	prefs := make([]jobs.NotificationPref, 0, len(req))
+	for c, on := range req {
+		if !known[c] {
+			http.Error(w, "unknown channel: "+c, http.StatusBadRequest)
+			return
+		}
+		if !on && h.Channels.Required(c) {
+			http.Error(w, "channel cannot be turned off: "+c, http.StatusBadRequest)
+			return
+		}
+		prefs = append(prefs, jobs.NotificationPref{UserID: uid, Channel: c, Enabled: on, UpdatedAt: time.Now()})
+	}
+
+	// mail goes to the account address; refuse it now rather than at send time
+	if req["email"] {
+		var u auth.User
+		if err := h.DB.Select("email").First(&u, uid).Error; err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := jobs.ParseAddress(u.Email); err != nil {
+			http.Error(w, "account email is not a valid address", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(prefs) > 0 {
+		if err := h.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&prefs).Error; err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.Get(w, r)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tell/internal/auth"
+	"tell/internal/jobs"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	DB *gorm.DB
+}
+
+type notificationDTO struct {
+	ID        uint64     `json:"id"`
+	Kind      string     `json:"kind"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	MemoID    *uint64    `json:"memo_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// List: GET /notifications?unread=true&before=<id>&limit=
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	q := h.DB.Model(&jobs.Notification{}).Where("user_id = ?", uid)
+	if strings.TrimSpace(strings.ToLower(r.URL.Query().Get("unread"))) == "true" {
+		q = q.Where("read_at is null")
+	}
+	if v := strings.TrimSpace(r.URL.Query().Get("before")); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+		q = q.Where("id < ?", n)
+	}
+
+	limit := 50
+	if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 200 {
+			limit = n
+		}
+	}
+
+	var rows []jobs.Notification
+	if err := q.Order("id desc").Limit(limit).Find(&rows).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]notificationDTO, 0, len(rows))
+	for _, n := range rows {
+		out = append(out, notificationDTO{
+			ID:        n.ID,
+			Kind:      n.Kind,
+			Title:     n.Title,
+			Body:      n.Body,
+			MemoID:    n.MemoID,
+			ReadAt:    n.ReadAt,
+			CreatedAt: n.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *NotificationHandler) UnreadCount(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var n int64
+	if err := h.DB.Model(&jobs.Notification{}).
+		Where("user_id = ? AND read_at is null", uid).
+		Count(&n).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"unread": n})
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var n jobs.Notification
+	if err := h.DB.Where("id = ? AND user_id = ?", id64, uid).First(&n).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	// already-read keeps its first read_at
+	if err := h.DB.Model(&jobs.Notification{}).
+		Where("id = ? AND read_at is null", id64).
+		Update("read_at", time.Now()).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	res := h.DB.Model(&jobs.Notification{}).
+		Where("user_id = ? AND read_at is null", uid).
+		Update("read_at", time.Now())
+	if res.Error != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"marked": res.RowsAffected})
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"tell/internal/auth"
+	"tell/internal/webpush"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PushHandler manages the user's browser push subscriptions.
+type PushHandler struct {
+	DB        *gorm.DB
+	PublicKey string // VAPID applicationServerKey; empty = push disabled
+}
+
+// pushSubscriptionReq is the browser's PushSubscription.toJSON().
+type pushSubscriptionReq struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+type pushSubscriptionDTO struct {
+	ID       uint64 `json:"id"`
+	Endpoint string `json:"endpoint"`
+}
+
+func (h *PushHandler) VAPIDKey(w http.ResponseWriter, r *http.Request) {
+	if h.PublicKey == "" {
+		http.Error(w, "push not configured", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"public_key": h.PublicKey})
+}
+
+// Subscribe registers a subscription; re-registering an endpoint updates it.
+// An endpoint registered by another user is a 409, never taken over.
+func (h *PushHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var req pushSubscriptionReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	req.Endpoint = strings.TrimSpace(req.Endpoint)
+	u, err := url.Parse(req.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		http.Error(w, "invalid endpoint (https required)", http.StatusBadRequest)
+		return
+	}
+	if k, err := webpush.DecodeKey(req.Keys.P256dh); err != nil || len(k) != 65 {
+		http.Error(w, "invalid keys.p256dh", http.StatusBadRequest)
+		return
+	}
+	if k, err := webpush.DecodeKey(req.Keys.Auth); err != nil || len(k) != 16 {
+		http.Error(w, "invalid keys.auth", http.StatusBadRequest)
+		return
+	}
+
+	sub := webpush.Subscription{
+		UserID:    uid,
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		UserAgent: r.UserAgent(),
+	}
+	res := h.DB.WithContext(r.Context()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "endpoint"}},
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "push_subscriptions.user_id = excluded.user_id"}}},
+		DoUpdates: clause.AssignmentColumns([]string{"p256dh", "auth", "user_agent"}),
+	}).Create(&sub)
+	if res.Error != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		http.Error(w, "endpoint registered by another user", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(pushSubscriptionDTO{ID: sub.ID, Endpoint: sub.Endpoint})
+}
+
+func (h *PushHandler) List(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var subs []webpush.Subscription
+	if err := h.DB.WithContext(r.Context()).Where("user_id = ?", uid).Order("id asc").Find(&subs).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	out := make([]pushSubscriptionDTO, 0, len(subs))
+	for _, s := range subs {
+		out = append(out, pushSubscriptionDTO{ID: s.ID, Endpoint: s.Endpoint})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Unsubscribe takes {"endpoint": "..."}, which is what the browser knows.
+func (h *PushHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var req struct {
+		Endpoint string `json:"endpoint"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Endpoint) == "" {
+		http.Error(w, "endpoint required", http.StatusBadRequest)
+		return
+	}
+
+	res := h.DB.WithContext(r.Context()).
+		Where("user_id = ? AND endpoint = ?", uid, strings.TrimSpace(req.Endpoint)).
+		Delete(&webpush.Subscription{})
+	if res.Error != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tell/internal/auth"
+	"tell/internal/jobs"
+	"tell/internal/memo"
+
+	"gorm.io/gorm"
+)
+
+// maxReminderRange caps to - from of GET /reminders.
+const maxReminderRange = 92 * 24 * time.Hour
+
+type ReminderHandler struct {
+	DB *gorm.DB
+}
+
+type upcomingReminderDTO struct {
+	MemoID     uint64    `json:"memo_id"`
+	ReminderID string    `json:"reminder_id"`
+	RemindAt   time.Time `json:"remind_at"`
+	Content    string    `json:"content"`
+	Tags       []string  `json:"tags"`
+	Rule       string    `json:"rrule,omitempty"`
+	Snoozed    bool      `json:"snoozed,omitempty"`
+}
+
+type reminderDayDTO struct {
+	Date      string                `json:"date"`
+	Reminders []upcomingReminderDTO `json:"reminders"`
+}
+
+type upcomingResp struct {
+	Timezone string           `json:"timezone"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Days     []reminderDayDTO `json:"days"`
+}
+
+// Upcoming: GET /reminders?from=&to=
+// from/to are RFC3339, a local time, or a date (midnight) in the user's
+// timezone; the default is the next 7 days.
+func (h *ReminderHandler) Upcoming(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+	db := h.DB.WithContext(r.Context())
+
+	settings, err := auth.LoadSettings(db, uid)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	loc := settings.Location()
+
+	from := time.Now()
+	if v := strings.TrimSpace(r.URL.Query().Get("from")); v != "" {
+		if from, err = parseRangeBound(v, loc); err != nil {
+			http.Error(w, "invalid from", http.StatusBadRequest)
+			return
+		}
+	}
+	to := from.Add(7 * 24 * time.Hour)
+	if v := strings.TrimSpace(r.URL.Query().Get("to")); v != "" {
+		if to, err = parseRangeBound(v, loc); err != nil {
+			http.Error(w, "invalid to", http.StatusBadRequest)
+			return
+		}
+	}
+	if !to.After(from) {
+		http.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) > maxReminderRange {
+		http.Error(w, "range too large (max 92 days)", http.StatusBadRequest)
+		return
+	}
+
+	occs, err := memo.Upcoming(db, uid, from, to, loc)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := upcomingResp{Timezone: loc.String(), From: from, To: to, Days: []reminderDayDTO{}}
+	for _, o := range occs {
+		at := o.At.In(loc)
+		date := at.Format("2006-01-02")
+		if n := len(resp.Days); n == 0 || resp.Days[n-1].Date != date {
+			resp.Days = append(resp.Days, reminderDayDTO{Date: date})
+		}
+		tags := o.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		day := &resp.Days[len(resp.Days)-1]
+		day.Reminders = append(day.Reminders, upcomingReminderDTO{
+			MemoID:     o.MemoID,
+			ReminderID: o.ReminderID,
+			RemindAt:   at,
+			Content:    o.Content,
+			Tags:       tags,
+			Rule:       o.Rule,
+			Snoozed:    o.Snoozed,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func parseRangeBound(v string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	var err error
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+type reminderHistoryDTO struct {
+	JobID      uint64    `json:"job_id"`
+	MemoID     uint64    `json:"memo_id"`
+	ReminderID string    `json:"reminder_id"`
+	Outcome    string    `json:"outcome"`          // fired, skipped, failed; done for jobs from before outcomes were kept
+	Reason     string    `json:"reason,omitempty"` // why it was skipped: archived, deleted, cleared
+	RunAt      time.Time `json:"run_at"`           // last scheduled run (moves with retries and quiet hours)
+	FinishedAt time.Time `json:"finished_at"`
+	Attempts   int       `json:"attempts"`
+	LastError  *string   `json:"last_error"`
+	Content    *string   `json:"content"` // nil once the memo is gone
+}
+
+type reminderHistoryRow struct {
+	ID         uint64
+	Status     string
+	Result     *string
+	MemoID     uint64
+	ReminderID string
+	RunAt      time.Time
+	UpdatedAt  time.Time
+	Attempts   int
+	LastError  *string
+	Content    *string
+}
+
+// History: GET /reminders/history?outcome=fired|skipped|failed&before=<job id>&limit=
+// lists finished REMINDER_DISPATCH jobs, newest first.
+func (h *ReminderHandler) History(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	q := h.DB.WithContext(r.Context()).Table("jobs j").
+		Select(`j.id, j.status, j.result, j.run_at, j.updated_at, j.attempts, j.last_error,
+			(j.payload->>'memo_id')::bigint as memo_id,
+			coalesce(j.payload->>'reminder_id', ?) as reminder_id,
+			p.content`, memo.DefaultReminderID).
+		Joins("left join memo_projections p on p.memo_id = (j.payload->>'memo_id')::bigint and p.user_id = j.user_id").
+		Where("j.user_id = ? AND j.type = 'REMINDER_DISPATCH'", uid)
+
+	switch strings.TrimSpace(r.URL.Query().Get("outcome")) {
+	case "":
+		q = q.Where("j.status in ('DONE','FAILED')")
+	case "fired":
+		q = q.Where("j.status = 'DONE' AND j.result = ?", jobs.ResultFired)
+	case "skipped":
+		q = q.Where("j.status = 'DONE' AND j.result like 'skipped:%'")
+	case "failed":
+		q = q.Where("j.status = 'FAILED'")
+	default:
+		http.Error(w, "invalid outcome", http.StatusBadRequest)
+		return
+	}
+	if v := strings.TrimSpace(r.URL.Query().Get("before")); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+		q = q.Where("j.id < ?", n)
+	}
+
+	limit := 50
+	if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 200 {
+			limit = n
+		}
+	}
+
+	var rows []reminderHistoryRow
+	if err := q.Order("j.id desc").Limit(limit).Scan(&rows).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]reminderHistoryDTO, 0, len(rows))
+	for _, row := range rows {
+		d := reminderHistoryDTO{
+			JobID:      row.ID,
+			MemoID:     row.MemoID,
+			ReminderID: row.ReminderID,
+			RunAt:      row.RunAt,
+			FinishedAt: row.UpdatedAt,
+			Attempts:   row.Attempts,
+			LastError:  row.LastError,
+			Content:    row.Content,
+		}
+		switch {
+		case row.Status == "FAILED":
+			d.Outcome = "failed"
+		case row.Result == nil:
+			d.Outcome = "done"
+		default:
+			d.Outcome, d.Reason, _ = strings.Cut(*row.Result, ":")
+		}
+		out = append(out, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
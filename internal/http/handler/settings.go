@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"tell/internal/auth"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SettingsHandler serves the user's timezone and quiet hours.
+type SettingsHandler struct {
+	DB *gorm.DB
+}
+
+type settingsDTO struct {
+	Timezone   string  `json:"timezone"`
+	QuietStart *string `json:"quiet_start"` // "HH:MM"
+	QuietEnd   *string `json:"quiet_end"`
+}
+
+func (h *SettingsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	s, err := auth.LoadSettings(h.DB.WithContext(r.Context()), uid)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(settingsDTO{Timezone: s.Timezone, QuietStart: s.QuietStart, QuietEnd: s.QuietEnd})
+}
+
+// Put replaces the settings. Send both quiet_start and quiet_end, or
+// neither to turn quiet hours off.
+func (h *SettingsHandler) Put(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var req settingsDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+
+	req.Timezone = strings.TrimSpace(req.Timezone)
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		http.Error(w, "invalid timezone", http.StatusBadRequest)
+		return
+	}
+	if (req.QuietStart == nil) != (req.QuietEnd == nil) {
+		http.Error(w, "quiet_start and quiet_end go together", http.StatusBadRequest)
+		return
+	}
+	for _, v := range []*string{req.QuietStart, req.QuietEnd} {
+		if v == nil {
+			continue
+		}
+		if _, err := auth.ParseClock(*v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s := auth.UserSettings{
+		UserID:     uid,
+		Timezone:   req.Timezone,
+		QuietStart: req.QuietStart,
+		QuietEnd:   req.QuietEnd,
+		UpdatedAt:  time.Now(),
+	}
+	if err := h.DB.WithContext(r.Context()).Clauses(clause.OnConflict{UpdateAll: true}).Create(&s).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.Get(w, r)
+}
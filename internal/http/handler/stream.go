@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tell/internal/auth"
+	"tell/internal/memo"
+
+	"gorm.io/gorm"
+)
+
+// StreamHandler pushes the user's memo events as Server-Sent Events.
+type StreamHandler struct {
+	DB  *gorm.DB
+	Hub *memo.EventHub
+}
+
+const (
+	streamBatch     = 100
+	streamHeartbeat = 25 * time.Second
+)
+
+// Stream: GET /memos/stream. Resumes after Last-Event-ID (header, or the
+// last_event_id query param for clients that can't set it); without it only
+// new events are sent.
+func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	lastStr := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if lastStr == "" {
+		lastStr = strings.TrimSpace(r.URL.Query().Get("last_event_id"))
+	}
+
+	// subscribe before reading, so nothing committed in between is missed
+	wake, cancel := h.Hub.Subscribe(uid)
+	defer cancel()
+
+	var last uint64
+	if lastStr != "" {
+		n, err := strconv.ParseUint(lastStr, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		last = n
+	} else if err := h.DB.WithContext(r.Context()).Model(&memo.MemoEvent{}).
+		Where("user_id = ?", uid).
+		Select("coalesce(max(id), 0)").
+		Scan(&last).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		// drain everything after last
+		for {
+			evs, err := memo.EventsAfter(h.DB.WithContext(r.Context()), uid, last, streamBatch)
+			if err != nil {
+				return
+			}
+			dtos, err := toMemoEventDTOs(evs)
+			if err != nil {
+				return
+			}
+			for _, d := range dtos {
+				b, _ := json.Marshal(d)
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: memo_event\ndata: %s\n\n", d.ID, b); err != nil {
+					return
+				}
+				last = d.ID
+			}
+			flusher.Flush()
+			if len(evs) < streamBatch {
+				break
+			}
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+		case <-heartbeat.C:
+			// also re-queries, in case a notification was lost
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
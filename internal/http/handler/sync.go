@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"tell/internal/auth"
+	"tell/internal/memo"
+)
+
+// SyncHandler is the offline-first batch endpoint for mobile clients.
+type SyncHandler struct {
+	Svc *memo.Service
+}
+
+type syncEventReq struct {
+	ClientID       string  `json:"client_id"`
+	MemoID         uint64  `json:"memo_id"`
+	MemoClientID   string  `json:"memo_client_id"` // client_id of a CREATED earlier in the batch
+	Type           string  `json:"type"`
+	Content        *string `json:"content"`
+	RemindAt       *string `json:"remind_at"`
+	RRule          *string `json:"rrule"`
+	Snooze         string  `json:"snooze"`
+	ReminderID     string  `json:"reminder_id"`
+	TZ             string  `json:"tz"`
+	ToEventID      *uint64 `json:"to_event_id"`
+	BaseVersion    *uint64 `json:"base_version"`
+	IdempotencyKey *string `json:"idempotency_key"`
+}
+
+type syncReq struct {
+	Cursor uint64         `json:"cursor"`
+	Events []syncEventReq `json:"events"`
+}
+
+type syncResultDTO struct {
+	ClientID   string `json:"client_id"`
+	Status     string `json:"status"`
+	MemoID     uint64 `json:"memo_id,omitempty"`
+	Version    uint64 `json:"version,omitempty"`
+	ConflictOf uint64 `json:"conflict_of,omitempty"`
+	Code       string `json:"code,omitempty"`
+}
+
+type syncResp struct {
+	Results    []syncResultDTO `json:"results"`
+	Events     []memoEventDTO  `json:"events"`
+	NextCursor uint64          `json:"next_cursor"`
+	HasMore    bool            `json:"has_more"`
+}
+
+const maxSyncEvents = 200
+
+func (h *SyncHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var req syncReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	if len(req.Events) > maxSyncEvents {
+		http.Error(w, "too many events", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	in := memo.SyncInput{UserID: uid, Cursor: req.Cursor}
+	for _, e := range req.Events {
+		ev := memo.SyncEvent{
+			ClientID:     e.ClientID,
+			MemoID:       e.MemoID,
+			MemoClientID: strings.TrimSpace(e.MemoClientID),
+			Type:         strings.TrimSpace(strings.ToUpper(e.Type)),
+			Content:      e.Content,
+			ToEventID:    e.ToEventID,
+			BaseVersion:  e.BaseVersion,
+		}
+		if e.RemindAt != nil && strings.TrimSpace(*e.RemindAt) != "" {
+			t, err := parseRemindAt(h.Svc.DB.WithContext(r.Context()), uid, *e.RemindAt)
+			if err != nil {
+				http.Error(w, "invalid remind_at (RFC3339 or local 2006-01-02T15:04)", http.StatusBadRequest)
+				return
+			}
+			ev.RemindAt = &t
+		}
+		rule, ok := parseRRule(w, e.RRule, ev.RemindAt)
+		if !ok {
+			return
+		}
+		ev.RemindRule = rule
+		ev.Snooze = e.Snooze
+		ev.ReminderID = strings.TrimSpace(e.ReminderID)
+		if ev.Location, ok = parseTZ(w, e.TZ); !ok {
+			return
+		}
+		if e.IdempotencyKey != nil {
+			if k := strings.TrimSpace(*e.IdempotencyKey); k != "" {
+				ev.IdemKey = &k
+			}
+		}
+		in.Events = append(in.Events, ev)
+	}
+
+	res, err := h.Svc.Sync(r.Context(), in)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	evs, err := toMemoEventDTOs(res.Events)
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	out := syncResp{
+		Results:    make([]syncResultDTO, 0, len(res.Results)),
+		Events:     evs,
+		NextCursor: res.NextCursor,
+		HasMore:    res.HasMore,
+	}
+	for _, rr := range res.Results {
+		out.Results = append(out.Results, syncResultDTO{
+			ClientID:   rr.ClientID,
+			Status:     rr.Status,
+			MemoID:     rr.MemoID,
+			Version:    rr.Version,
+			ConflictOf: rr.ConflictOf,
+			Code:       rr.Code,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tell/internal/auth"
+	"tell/internal/memo"
+	"tell/internal/netguard"
+	"tell/internal/webhook"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	DB *gorm.DB
+}
+
+type createWebhookReq struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"` // optional, generated when empty
+	EventTypes []string `json:"event_types"`
+}
+
+type webhookDTO struct {
+	ID         uint64    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"` // only on create
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type webhookDeliveryDTO struct {
+	ID         uint64    `json:"id"`
+	JobID      uint64    `json:"job_id"`
+	EventID    uint64    `json:"event_id"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"`
+	Error      *string   `json:"error"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func toWebhookDTO(s webhook.Subscription) webhookDTO {
+	types := []string(s.EventTypes)
+	if types == nil {
+		types = []string{}
+	}
+	return webhookDTO{
+		ID:         s.ID,
+		URL:        s.URL,
+		EventTypes: types,
+		Active:     s.Active,
+		CreatedAt:  s.CreatedAt,
+	}
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var req createWebhookReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "invalid url", http.StatusBadRequest)
+		return
+	}
+	// hostnames are checked again after DNS resolution, at delivery
+	if ip, err := netip.ParseAddr(strings.Trim(u.Hostname(), "[]")); (err == nil && netguard.Blocked(ip)) || strings.EqualFold(u.Hostname(), "localhost") {
+		http.Error(w, "url points to a private address", http.StatusBadRequest)
+		return
+	}
+
+	secret := strings.TrimSpace(req.Secret)
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	types := pq.StringArray{}
+	for _, t := range req.EventTypes {
+		if t = strings.TrimSpace(strings.ToUpper(t)); t != "" {
+			if !memo.KnownEventType(t) {
+				http.Error(w, "unknown event type: "+t, http.StatusBadRequest)
+				return
+			}
+			types = append(types, t)
+		}
+	}
+
+	sub := webhook.Subscription{
+		UserID:     uid,
+		URL:        u.String(),
+		Secret:     secret,
+		EventTypes: types,
+		Active:     true,
+	}
+	if err := h.DB.Create(&sub).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	out := toWebhookDTO(sub)
+	out.Secret = secret
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	var subs []webhook.Subscription
+	if err := h.DB.Where("user_id = ?", uid).Order("id asc").Find(&subs).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]webhookDTO, 0, len(subs))
+	for _, s := range subs {
+		out = append(out, toWebhookDTO(s))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	// pending deliveries are dropped by the worker once the row is gone
+	res := h.DB.Where("id = ? AND user_id = ?", id64, uid).Delete(&webhook.Subscription{})
+	if res.Error != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	uid, _ := auth.UserIDFromContext(r.Context())
+
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 200 {
+			limit = n
+		}
+	}
+
+	var sub webhook.Subscription
+	if err := h.DB.Where("id = ? AND user_id = ?", id64, uid).First(&sub).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	var rows []webhook.Delivery
+	if err := h.DB.Where("subscription_id = ? AND user_id = ?", id64, uid).
+		Order("id desc").Limit(limit).Find(&rows).Error; err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]webhookDeliveryDTO, 0, len(rows))
+	for _, d := range rows {
+		out = append(out, webhookDeliveryDTO{
+			ID:         d.ID,
+			JobID:      d.JobID,
+			EventID:    d.EventID,
+			Attempt:    d.Attempt,
+			StatusCode: d.StatusCode,
+			Error:      d.Error,
+			DurationMs: d.DurationMs,
+			CreatedAt:  d.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
@@ -9,9 +9,9 @@ import (
 func CORS(allowedOrigins []string, allowCredentials bool) func(http.Handler) http.Handler {
 	return cors.Handler(cors.Options{
 		AllowedOrigins:   allowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type", "Idempotency-Key"},
-		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "Idempotency-Key", "If-Match", "If-None-Match", "Last-Event-ID"},
+		ExposedHeaders:   []string{"X-Request-Id", "ETag"},
 		AllowCredentials: allowCredentials,
 		MaxAge:           300,
 	})
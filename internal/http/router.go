@@ -7,6 +7,7 @@ import (
 	"tell/internal/config"
 	"tell/internal/http/handler"
 	mw "tell/internal/http/middleware"
+	"tell/internal/jobs"
 	"tell/internal/memo"
 
 	"github.com/go-chi/chi/v5"
@@ -14,7 +15,7 @@ import (
 	"gorm.io/gorm"
 )
 
-func NewRouter(cfg config.Config, db *gorm.DB, jwtSvc *auth.JWT) http.Handler {
+func NewRouter(cfg config.Config, db *gorm.DB, jwtSvc *auth.JWT, hub *memo.EventHub, notifiers *jobs.Notifiers) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(chimw.RequestID)
@@ -37,9 +38,23 @@ func NewRouter(cfg config.Config, db *gorm.DB, jwtSvc *auth.JWT) http.Handler {
 	me := &handler.MeHandler{}
 	r.With(auth.RequireAuth(jwtSvc)).Get("/me", me.Me)
 
-	memoSvc := &memo.Service{DB: db}
+	prefs := &handler.NotificationPrefsHandler{DB: db, Channels: notifiers}
+	r.With(auth.RequireAuth(jwtSvc)).Get("/me/notification-channels", prefs.Get)
+	r.With(auth.RequireAuth(jwtSvc)).Put("/me/notification-channels", prefs.Put)
+
+	settings := &handler.SettingsHandler{DB: db}
+	r.With(auth.RequireAuth(jwtSvc)).Get("/me/settings", settings.Get)
+	r.With(auth.RequireAuth(jwtSvc)).Put("/me/settings", settings.Put)
+
+	cal := &handler.CalendarHandler{DB: db}
+	r.With(auth.RequireAuth(jwtSvc)).Post("/me/calendar-feed", cal.Rotate)
+	r.With(auth.RequireAuth(jwtSvc)).Delete("/me/calendar-feed", cal.Revoke)
+	r.Get("/calendar/{token}.ics", cal.Feed)
+
+	memoSvc := &memo.Service{DB: db, SnapshotEvery: cfg.SnapshotEvery}
 	memoH := &handler.MemoHandler{Svc: memoSvc, DB: db}
-	memoRead := &handler.MemoReadHandler{DB: db}
+	memoRead := &handler.MemoReadHandler{DB: db, Svc: memoSvc}
+	stream := &handler.StreamHandler{DB: db, Hub: hub}
 
 	r.Route("/memos", func(r chi.Router) {
 		r.Use(auth.RequireAuth(jwtSvc))
@@ -48,9 +63,56 @@ func NewRouter(cfg config.Config, db *gorm.DB, jwtSvc *auth.JWT) http.Handler {
 		r.Get("/", memoRead.List)
 
 		r.Get("/tags", memoRead.Tags)
+		r.Get("/stream", stream.Stream)
 
+		r.Get("/{id}", memoRead.Get)
 		r.Post("/{id}/events", memoH.AppendEvent)
 		r.Get("/{id}/timeline", memoRead.Timeline)
+		r.Get("/{id}/diff", memoRead.Diff)
+	})
+
+	feed := &handler.EventFeedHandler{DB: db, Hub: hub}
+	r.With(auth.RequireAuth(jwtSvc)).Get("/events", feed.List)
+
+	syncH := &handler.SyncHandler{Svc: memoSvc}
+	r.With(auth.RequireAuth(jwtSvc)).Post("/sync", syncH.Sync)
+
+	reminders := &handler.ReminderHandler{DB: db}
+	r.Route("/reminders", func(r chi.Router) {
+		r.Use(auth.RequireAuth(jwtSvc))
+
+		r.Get("/", reminders.Upcoming)
+		r.Get("/history", reminders.History)
+	})
+
+	inbox := &handler.NotificationHandler{DB: db}
+	r.Route("/notifications", func(r chi.Router) {
+		r.Use(auth.RequireAuth(jwtSvc))
+
+		r.Get("/", inbox.List)
+		r.Get("/unread-count", inbox.UnreadCount)
+		r.Post("/read-all", inbox.MarkAllRead)
+		r.Post("/{id}/read", inbox.MarkRead)
+	})
+
+	push := &handler.PushHandler{DB: db, PublicKey: cfg.VAPIDPublicKey}
+	r.Get("/push/vapid-public-key", push.VAPIDKey)
+	r.Route("/push/subscriptions", func(r chi.Router) {
+		r.Use(auth.RequireAuth(jwtSvc))
+
+		r.Post("/", push.Subscribe)
+		r.Get("/", push.List)
+		r.Delete("/", push.Unsubscribe)
+	})
+
+	hooks := &handler.WebhookHandler{DB: db}
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(auth.RequireAuth(jwtSvc))
+
+		r.Post("/", hooks.Create)
+		r.Get("/", hooks.List)
+		r.Delete("/{id}", hooks.Delete)
+		r.Get("/{id}/deliveries", hooks.Deliveries)
 	})
 
 	return r
@@ -0,0 +1,175 @@
+// Package ical renders reminders as an RFC 5545 calendar feed.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event is one VEVENT. A point in time with an alarm at Start.
+type Event struct {
+	UID         string
+	Start       time.Time
+	Rule        string // RRULE value; empty for a single occurrence
+	Summary     string
+	Description string
+	Categories  []string
+	Stamp       time.Time // last change, for DTSTAMP
+}
+
+// Calendar is a VCALENDAR whose times are written in Loc.
+type Calendar struct {
+	Name   string
+	Loc    *time.Location
+	Events []Event
+}
+
+// tzYears is how far past the last event start VTIMEZONE transitions are
+// listed; recurring events need the zone's future offsets.
+const tzYears = 5
+
+// Write renders c with CRLF line ends and folded lines.
+func Write(w io.Writer, c Calendar) error {
+	b := &builder{}
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:-//Tell//Reminders//EN")
+	b.line("CALSCALE:GREGORIAN")
+	b.line("METHOD:PUBLISH")
+	b.line("X-WR-CALNAME:" + escape(c.Name))
+	b.line("X-WR-TIMEZONE:" + c.Loc.String())
+	b.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	b.line("X-PUBLISHED-TTL:PT1H")
+
+	local := c.Loc != time.UTC && len(c.Events) > 0
+	if local {
+		from, to := c.Events[0].Start, c.Events[0].Start
+		for _, e := range c.Events {
+			if e.Start.Before(from) {
+				from = e.Start
+			}
+			if e.Start.After(to) {
+				to = e.Start
+			}
+		}
+		writeTimezone(b, c.Loc, from, to.AddDate(tzYears, 0, 0))
+	}
+
+	for _, e := range c.Events {
+		b.line("BEGIN:VEVENT")
+		b.line("UID:" + e.UID)
+		b.line("DTSTAMP:" + e.Stamp.UTC().Format("20060102T150405Z"))
+		if local {
+			b.line("DTSTART;TZID=" + c.Loc.String() + ":" + e.Start.In(c.Loc).Format("20060102T150405"))
+		} else {
+			b.line("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+		}
+		if e.Rule != "" {
+			b.line("RRULE:" + e.Rule)
+		}
+		b.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			b.line("DESCRIPTION:" + escape(e.Description))
+		}
+		if len(e.Categories) > 0 {
+			cats := make([]string, len(e.Categories))
+			for i, c := range e.Categories {
+				cats[i] = escape(c)
+			}
+			b.line("CATEGORIES:" + strings.Join(cats, ","))
+		}
+		b.line("TRANSP:TRANSPARENT")
+		b.line("BEGIN:VALARM")
+		b.line("ACTION:DISPLAY")
+		b.line("DESCRIPTION:" + escape(e.Summary))
+		b.line("TRIGGER:PT0S")
+		b.line("END:VALARM")
+		b.line("END:VEVENT")
+	}
+
+	b.line("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeTimezone lists loc's observances from the one in effect at from
+// through the transitions before to.
+func writeTimezone(b *builder, loc *time.Location, from, to time.Time) {
+	b.line("BEGIN:VTIMEZONE")
+	b.line("TZID:" + loc.String())
+
+	t := from.In(loc)
+	start, end := t.ZoneBounds()
+	name, off := t.Zone()
+	prev := off
+	onset := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !start.IsZero() {
+		_, prev = start.Add(-time.Second).Zone()
+		onset = start
+	}
+	observance(b, t.IsDST(), onset, prev, off, name)
+
+	for !end.IsZero() && end.Before(to) {
+		t = end.In(loc)
+		name, next := t.Zone()
+		observance(b, t.IsDST(), end, off, next, name)
+		off = next
+		_, end = t.ZoneBounds()
+	}
+	b.line("END:VTIMEZONE")
+}
+
+func observance(b *builder, dst bool, onset time.Time, from, to int, name string) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	b.line("BEGIN:" + kind)
+	// DTSTART of an observance is local time in the offset it replaces
+	b.line("DTSTART:" + onset.In(time.FixedZone("", from)).Format("20060102T150405"))
+	b.line("TZOFFSETFROM:" + utcOffset(from))
+	b.line("TZOFFSETTO:" + utcOffset(to))
+	b.line("TZNAME:" + escape(name))
+	b.line("END:" + kind)
+}
+
+// utcOffset formats seconds east of UTC as +hhmm (with ss when needed).
+func utcOffset(sec int) string {
+	sign := "+"
+	if sec < 0 {
+		sign, sec = "-", -sec
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, sec/3600, sec/60%60)
+	if sec%60 != 0 {
+		s += fmt.Sprintf("%02d", sec%60)
+	}
+	return s
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+// escape escapes a TEXT value.
+func escape(s string) string { return textEscaper.Replace(s) }
+
+type builder struct{ strings.Builder }
+
+// line writes one content line folded at 75 octets without splitting a
+// UTF-8 sequence.
+func (b *builder) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		b.WriteString(s[:i])
+		b.WriteString("\r\n ")
+		s = s[i:]
+		limit = 74 // the leading space counts
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
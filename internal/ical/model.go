@@ -0,0 +1,35 @@
+package ical
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// Feed is a user's calendar feed. Only the SHA-256 of the URL token is
+// stored; rotating replaces it, which revokes the old URL.
+type Feed struct {
+	UserID     uint64     `gorm:"primaryKey"`
+	TokenHash  string     `gorm:"type:text;uniqueIndex;not null"`
+	CreatedAt  time.Time  `gorm:"not null;default:now()"`
+	LastUsedAt *time.Time `gorm:"type:timestamptz"`
+}
+
+func (Feed) TableName() string { return "calendar_feeds" }
+
+// NewToken returns a random URL-safe feed token and its hash.
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
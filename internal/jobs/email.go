@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailNotifier sends plain-text mail over SMTP to the user's account
+// address. Username may be empty for servers without AUTH (e.g. a local
+// test server).
+type EmailNotifier struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+
+	DB *gorm.DB
+}
+
+func (e *EmailNotifier) Send(ctx context.Context, m Message) error {
+	var to string
+	if err := e.DB.WithContext(ctx).Raw(`select email from users where id = ?`, m.UserID).Scan(&to).Error; err != nil {
+		return err
+	}
+	if to == "" {
+		return Permanent(fmt.Errorf("user %d has no email", m.UserID))
+	}
+	msg, err := buildMail(e.From, to, m)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	var a smtp.Auth
+	if e.Username != "" {
+		host, _, _ := strings.Cut(e.Addr, ":")
+		a = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+	from, _ := ParseAddress(e.From)
+	rcpt, _ := ParseAddress(to)
+	return sendMail(ctx, e.Addr, a, from.Address, rcpt.Address, msg)
+}
+
+// ParseAddress is mail.ParseAddress refusing CR and LF, which would let the
+// value add lines to the mail header or the SMTP conversation.
+func ParseAddress(s string) (*mail.Address, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		return nil, fmt.Errorf("invalid address %q: contains a line break", s)
+	}
+	a, err := mail.ParseAddress(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	return a, nil
+}
+
+// smtpTimeout bounds a send when ctx has no deadline of its own.
+const smtpTimeout = time.Minute
+
+// sendMail is smtp.SendMail bound to ctx: the dial honours it, its deadline
+// (or smtpTimeout) applies to the whole conversation and cancelling it
+// aborts a send in progress.
+func sendMail(ctx context.Context, addr string, a smtp.Auth, from, to string, msg []byte) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn, err := (&net.Dialer{Deadline: deadline}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	host, _, _ := strings.Cut(addr, ":")
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return ctxErr(ctx, err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return ctxErr(ctx, err)
+		}
+	}
+	if a != nil {
+		if err := c.Auth(a); err != nil {
+			return ctxErr(ctx, err)
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return ctxErr(ctx, err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return ctxErr(ctx, err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return ctxErr(ctx, err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return ctxErr(ctx, err)
+	}
+	if err := w.Close(); err != nil {
+		return ctxErr(ctx, err)
+	}
+	return ctxErr(ctx, c.Quit())
+}
+
+// ctxErr reports a cancelled ctx rather than the i/o timeout it caused.
+func ctxErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	// the conn deadline can fire just before ctx's own timer
+	if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+		return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return err
+}
+
+func buildMail(from, to string, m Message) ([]byte, error) {
+	fa, err := ParseAddress(from)
+	if err != nil {
+		return nil, err
+	}
+	ta, err := ParseAddress(to)
+	if err != nil {
+		return nil, err
+	}
+	subject := m.Title
+	if subject == "" {
+		subject = "Tell"
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + fa.String() + "\r\n")
+	b.WriteString("To: " + ta.String() + "\r\n")
+	b.WriteString("Subject: " + strings.NewReplacer("\r", " ", "\n", " ").Replace(subject) + "\r\n")
+	b.WriteString("Date: " + m.At.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	// normalize line endings; dot-stuffing is done by net/smtp
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one connection and speaks just enough SMTP for
+// sendMail; the DATA it receives is sent on the returned channel. With
+// stall set it greets nobody and holds the connection open.
+func fakeSMTP(t *testing.T, stall bool) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	got := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if stall {
+			conn.Read(make([]byte, 1)) // until the client hangs up
+			return
+		}
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 fake ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250-fake")
+				reply("250 8BITMIME")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				data.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 ok")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				got <- data.String()
+				return
+			default:
+				reply("502 unknown")
+			}
+		}
+	}()
+	return ln.Addr().String(), got
+}
+
+func TestSendMail(t *testing.T) {
+	addr, got := fakeSMTP(t, false)
+	msg, err := buildMail("Tell <tell@example.com>", "ana@example.com", Message{Title: "Beli susu", Body: "dua liter\n.\nlagi", At: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sendMail(context.Background(), addr, nil, "tell@example.com", "ana@example.com", msg); err != nil {
+		t.Fatal(err)
+	}
+	s := <-got
+	for _, want := range []string{"MAIL FROM:<tell@example.com>", "RCPT TO:<ana@example.com>", "From: \"Tell\" <tell@example.com>\r\n", "To: <ana@example.com>\r\n", "Subject: Beli susu\r\n", "dua liter\r\n..\r\nlagi"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %q in:\n%s", want, s)
+		}
+	}
+}
+
+func TestBuildMailAddresses(t *testing.T) {
+	m := Message{Title: "x", At: time.Now()}
+	for _, tt := range []struct{ from, to string }{
+		{"tell@example.com", "ana@example.com\r\nBcc: all@example.com"},
+		{"tell@example.com\nBcc: all@example.com", "ana@example.com"},
+		{"tell@example.com", "bukan alamat"},
+		{"", "ana@example.com"},
+	} {
+		if _, err := buildMail(tt.from, tt.to, m); err == nil {
+			t.Errorf("buildMail(%q, %q) accepted", tt.from, tt.to)
+		}
+	}
+}
+
+func TestSendMailHonoursContext(t *testing.T) {
+	addr, _ := fakeSMTP(t, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := sendMail(ctx, addr, nil, "tell@example.com", "ana@example.com", []byte("x"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("took %v", d)
+	}
+
+	addr, _ = fakeSMTP(t, true)
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if err := sendMail(ctx, addr, nil, "tell@example.com", "ana@example.com", []byte("x")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want canceled", err)
+	}
+}
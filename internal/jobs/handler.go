@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Handler runs jobs of one type. The returned result is kept in Job.Result
+// when the job is done. Errors are retried per the type's Policy unless
+// they are Permanent; Defer puts the job back without counting an attempt.
+type Handler interface {
+	Handle(ctx context.Context, job *Job) (result string, err error)
+}
+
+type HandlerFunc func(ctx context.Context, job *Job) (string, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, job *Job) (string, error) { return f(ctx, job) }
+
+// Typed is a Handler whose payload is decoded into P first. A payload that
+// does not decode fails the job permanently.
+func Typed[P any](fn func(ctx context.Context, job *Job, p P) (string, error)) Handler {
+	return HandlerFunc(func(ctx context.Context, job *Job) (string, error) {
+		var p P
+		if err := json.Unmarshal(job.Payload, &p); err != nil {
+			return "", Permanent(fmt.Errorf("bad payload: %w", err))
+		}
+		return fn(ctx, job, p)
+	})
+}
+
+// Policy is how jobs of a type are run and retried.
+type Policy struct {
+	MaxAttempts int                             // 0 = the job row's MaxAttempts
+	Backoff     func(attempt int) time.Duration // nil = DefaultBackoff
+	Timeout     time.Duration                   // 0 = none, counted as untimedExpiry for the claim expiry
+}
+
+// DefaultBackoff is 2^attempt seconds, at most 10 minutes.
+func DefaultBackoff(attempt int) time.Duration {
+	sec := math.Min(math.Pow(2, float64(attempt)), 600)
+	return time.Duration(sec) * time.Second
+}
+
+func (p Policy) maxAttempts(job *Job) int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return job.MaxAttempts
+}
+
+func (p Policy) backoff(attempt int) time.Duration {
+	if p.Backoff != nil {
+		return p.Backoff(attempt)
+	}
+	return DefaultBackoff(attempt)
+}
+
+type registration struct {
+	handler Handler
+	policy  Policy
+}
+
+// Registry maps job types to their Handler and Policy.
+type Registry struct {
+	byType map[string]registration
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byType: map[string]registration{}}
+}
+
+// Register sets the handler of typ, replacing any earlier one.
+func (r *Registry) Register(typ string, h Handler, p Policy) {
+	r.byType[typ] = registration{handler: h, policy: p}
+}
+
+func (r *Registry) lookup(typ string) (registration, bool) {
+	reg, ok := r.byType[typ]
+	return reg, ok
+}
+
+const (
+	// untimedExpiry stands in for the timeout of a type without one.
+	untimedExpiry = 5 * time.Minute
+	// claimMargin is added to the longest timeout before a RUNNING job
+	// counts as stuck, so a slow settle does not requeue a finished job.
+	claimMargin = time.Minute
+)
+
+// claimExpiry is how long a job may stay RUNNING before it is requeued:
+// the longest handler timeout plus claimMargin.
+func (r *Registry) claimExpiry() time.Duration {
+	longest := time.Duration(0)
+	for _, reg := range r.byType {
+		t := reg.policy.Timeout
+		if t <= 0 {
+			t = untimedExpiry
+		}
+		longest = max(longest, t)
+	}
+	if longest == 0 {
+		longest = untimedExpiry
+	}
+	return longest + claimMargin
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying: the job fails right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+type deferError struct{ until time.Time }
+
+func (e *deferError) Error() string { return "deferred until " + e.until.Format(time.RFC3339) }
+
+// Defer asks for the job to run again at until, without counting an attempt.
+func Defer(until time.Time) error { return &deferError{until: until} }
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification is an in-app inbox entry.
+type Notification struct {
+	ID        uint64     `gorm:"primaryKey"`
+	UserID    uint64     `gorm:"index;not null"`
+	Kind      string     `gorm:"type:text;not null"` // reminder
+	Title     string     `gorm:"type:text;not null;default:''"`
+	Body      string     `gorm:"type:text;not null;default:''"`
+	MemoID    *uint64    `gorm:"index"`
+	ReadAt    *time.Time `gorm:"type:timestamptz"`
+	CreatedAt time.Time  `gorm:"index;not null;default:now()"`
+}
+
+// AddToInbox stores m in the user's inbox. Any job type can use it.
+func AddToInbox(db *gorm.DB, m Message) error {
+	n := Notification{
+		UserID:    m.UserID,
+		Kind:      m.Kind,
+		Title:     m.Title,
+		Body:      m.Body,
+		CreatedAt: m.At,
+	}
+	if m.MemoID != 0 {
+		id := m.MemoID
+		n.MemoID = &id
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	return db.Create(&n).Error
+}
+
+// InboxNotifier is the "inapp" channel. Register it with RegisterRequired:
+// the inbox is the record of every reminder, whatever else is turned off.
+type InboxNotifier struct {
+	DB *gorm.DB
+}
+
+func (n *InboxNotifier) Send(ctx context.Context, m Message) error {
+	return AddToInbox(n.DB.WithContext(ctx), m)
+}
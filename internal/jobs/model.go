@@ -1,12 +1,17 @@
 package jobs
 
-import "time"
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Job struct {
 	ID     uint64 `gorm:"primaryKey"`
 	UserID uint64 `gorm:"index;not null"`
 
-	Type    string `gorm:"type:text;not null"` // REMINDER_DISPATCH
+	Type    string `gorm:"type:text;not null"` // REMINDER_DISPATCH / SNAPSHOT_BACKFILL / WEBHOOK_DELIVERY
 	Payload []byte `gorm:"type:jsonb;not null;default:'{}'::jsonb"`
 
 	RunAt  time.Time `gorm:"index;not null"`
@@ -19,7 +24,23 @@ type Job struct {
 	LockedAt *time.Time `gorm:"type:timestamptz"`
 
 	LastError *string `gorm:"type:text"`
+	Result    *string `gorm:"type:text"` // outcome of a DONE job, e.g. "fired" or "skipped:archived"
 
 	CreatedAt time.Time `gorm:"not null;default:now()"`
 	UpdatedAt time.Time `gorm:"not null;default:now()"`
 }
+
+// JobsChannel is NOTIFY'd with a job's run_at (unix ms) when it is enqueued
+// or rescheduled, so idle workers wake up or shorten their sleep. Postgres
+// delivers it only when the tx commits.
+const JobsChannel = "jobs"
+
+func notifyJob(db *gorm.DB, runAt time.Time) error {
+	return db.Exec(`select pg_notify(?, ?)`, JobsChannel, strconv.FormatInt(runAt.UnixMilli(), 10)).Error
+}
+
+// AfterCreate covers every enqueue, including those made by other packages
+// inside their own tx.
+func (j *Job) AfterCreate(tx *gorm.DB) error {
+	return notifyJob(tx.Session(&gorm.Session{NewDB: true}), j.RunAt)
+}
@@ -0,0 +1,185 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Message is what a job sends to a user through a Notifier.
+type Message struct {
+	UserID uint64
+	MemoID uint64
+	Kind   string // reminder
+	Title  string
+	Body   string
+	At     time.Time
+}
+
+// Notifier delivers a Message over one channel (log, email, ...).
+type Notifier interface {
+	Send(ctx context.Context, m Message) error
+}
+
+type NotifierFunc func(ctx context.Context, m Message) error
+
+func (f NotifierFunc) Send(ctx context.Context, m Message) error { return f(ctx, m) }
+
+// Notifiers is the registry of channels. A channel is used for a user when
+// their NotificationPref says so, or by its default when they have none;
+// required channels are used for everyone.
+type Notifiers struct {
+	byName   map[string]Notifier
+	defaults map[string]bool
+	required map[string]bool
+}
+
+func NewNotifiers() *Notifiers {
+	return &Notifiers{byName: map[string]Notifier{}, defaults: map[string]bool{}, required: map[string]bool{}}
+}
+
+func (n *Notifiers) Register(name string, nt Notifier, enabledByDefault bool) {
+	n.byName[name] = nt
+	n.defaults[name] = enabledByDefault
+	delete(n.required, name)
+}
+
+// RegisterRequired adds a channel users cannot turn off, such as the
+// in-app inbox that lists every reminder.
+func (n *Notifiers) RegisterRequired(name string, nt Notifier) {
+	n.Register(name, nt, true)
+	n.required[name] = true
+}
+
+// Required reports whether name is a channel users cannot turn off.
+func (n *Notifiers) Required(name string) bool { return n.required[name] }
+
+// Names returns the registered channels, sorted.
+func (n *Notifiers) Names() []string {
+	out := make([]string, 0, len(n.byName))
+	for name := range n.byName {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Enabled returns the user's enabled channels, sorted.
+func (n *Notifiers) Enabled(db *gorm.DB, userID uint64) ([]string, error) {
+	var prefs []NotificationPref
+	if err := db.Where("user_id = ?", userID).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+	set := map[string]bool{}
+	for name, on := range n.defaults {
+		set[name] = on
+	}
+	for _, p := range prefs {
+		if _, ok := n.byName[p.Channel]; ok && !n.required[p.Channel] {
+			set[p.Channel] = p.Enabled
+		}
+	}
+
+	var out []string
+	for _, name := range n.Names() {
+		if set[name] {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+// NotificationPref turns a channel on or off for a user.
+type NotificationPref struct {
+	UserID    uint64    `gorm:"primaryKey"`
+	Channel   string    `gorm:"primaryKey;type:text"`
+	Enabled   bool      `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null;default:now()"`
+}
+
+// ChannelDelivery tracks one channel of one job, so a retried job only
+// resends the channels that failed.
+type ChannelDelivery struct {
+	JobID     uint64     `gorm:"primaryKey"`
+	Channel   string     `gorm:"primaryKey;type:text"`
+	UserID    uint64     `gorm:"index;not null"`
+	Status    string     `gorm:"not null"` // SENT / FAILED
+	Attempts  int        `gorm:"not null;default:0"`
+	LastError *string    `gorm:"type:text"`
+	SentAt    *time.Time `gorm:"type:timestamptz"`
+	UpdatedAt time.Time  `gorm:"not null;default:now()"`
+}
+
+// dispatch fans m out to every enabled channel of the user that has not
+// already succeeded for this job. The error lists the failed channels.
+func (w *Worker) dispatch(ctx context.Context, job *Job, m Message) ([]string, error) {
+	channels, err := w.notifiers().Enabled(w.DB, job.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []ChannelDelivery
+	if err := w.DB.Where("job_id = ?", job.ID).Find(&done).Error; err != nil {
+		return nil, err
+	}
+	prev := map[string]ChannelDelivery{}
+	for _, d := range done {
+		prev[d.Channel] = d
+	}
+
+	var sent []string
+	var errs []error
+	for _, ch := range channels {
+		d := prev[ch]
+		if d.Status == "SENT" {
+			sent = append(sent, ch)
+			continue
+		}
+		nt := w.notifiers().byName[ch]
+
+		d.JobID, d.Channel, d.UserID = job.ID, ch, job.UserID
+		d.Attempts++
+		d.UpdatedAt = time.Now()
+		if err := nt.Send(ctx, m); err != nil {
+			msg := err.Error()
+			d.Status, d.LastError = "FAILED", &msg
+			errs = append(errs, fmt.Errorf("%s: %w", ch, err))
+		} else {
+			now := time.Now()
+			d.Status, d.LastError, d.SentAt = "SENT", nil, &now
+			sent = append(sent, ch)
+		}
+
+		if err := w.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&d).Error; err != nil {
+			log.Printf("channel delivery record error: %v\n", err)
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (w *Worker) notifiers() *Notifiers {
+	if w.Notifiers != nil {
+		return w.Notifiers
+	}
+	return defaultNotifiers
+}
+
+var defaultNotifiers = func() *Notifiers {
+	n := NewNotifiers()
+	n.Register("log", LogNotifier{}, true)
+	return n
+}()
+
+// LogNotifier writes the message to the process log.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(_ context.Context, m Message) error {
+	log.Printf("[REMINDER] user=%d memo=%d content=%q\n", m.UserID, m.MemoID, m.Body)
+	return nil
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRequiredChannelIgnoresPrefs(t *testing.T) {
+	db := testDB(t)
+	if err := db.AutoMigrate(&NotificationPref{}); err != nil {
+		t.Fatal(err)
+	}
+	uid := testUser()
+	noop := NotifierFunc(func(context.Context, Message) error { return nil })
+
+	n := NewNotifiers()
+	n.RegisterRequired("inapp", noop)
+	n.Register("email", noop, true)
+	if !n.Required("inapp") || n.Required("email") {
+		t.Fatal("required flags")
+	}
+
+	off := []NotificationPref{{UserID: uid, Channel: "inapp"}, {UserID: uid, Channel: "email"}}
+	if err := db.Create(&off).Error; err != nil {
+		t.Fatal(err)
+	}
+	got, err := n.Enabled(db, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"inapp"}) {
+		t.Fatalf("enabled %v, want only inapp", got)
+	}
+}
@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Run claims and handles jobs until ctx is done, up to Concurrency at a
+// time, and waits for the running ones before returning. Between rounds it
+// sleeps until the next job is due, a NOTIFY on JobsChannel, or the poll
+// interval, which doubles (up to PollMax) while rounds find nothing; the
+// poll only matters when a notification is lost.
+func (w *Worker) Run(ctx context.Context) {
+	if w.Handlers == nil {
+		w.Handlers = NewRegistry()
+	}
+	w.registerBuiltins()
+	expiry := w.Handlers.claimExpiry()
+
+	n := max(w.Concurrency, 1)
+	batch := w.BatchSize
+	if batch <= 0 {
+		batch = n
+	}
+	pollMin, pollMax := w.PollMin, w.PollMax
+	if pollMin <= 0 {
+		pollMin = 800 * time.Millisecond
+	}
+	if pollMax <= 0 {
+		pollMax = 5 * time.Second
+		if w.DSN != "" {
+			pollMax = 30 * time.Second
+		}
+	}
+	pollMax = max(pollMax, pollMin)
+
+	notified := make(chan time.Time, 16)
+	if w.DSN != "" {
+		go w.listen(ctx, notified)
+	}
+
+	slots := make(chan struct{}, n) // one per running handler
+	freed := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	deadline := time.Now()
+	sleep := func(d time.Duration) {
+		timer.Reset(d)
+		deadline = time.Now().Add(d)
+	}
+	poll := pollMin
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-freed:
+		case at := <-notified:
+			if d := time.Until(at); d > 0 {
+				if at.Before(deadline) {
+					sleep(d)
+				}
+				continue
+			}
+		}
+
+		free := n - len(slots)
+		if free == 0 {
+			continue // a finishing handler sends on freed
+		}
+		want := min(free, batch)
+		claimed, err := w.Repo.Claim(w.ID, want, expiry)
+		if err != nil {
+			log.Printf("worker claim error: %v\n", err)
+			sleep(poll)
+			continue
+		}
+		for _, job := range claimed {
+			slots <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.handle(ctx, &job)
+				<-slots
+				select {
+				case freed <- struct{}{}:
+				default:
+				}
+			}()
+		}
+
+		if len(claimed) == want {
+			// maybe more due; claim again once a handler is free
+			poll = pollMin
+			if len(slots) < n {
+				sleep(0)
+			}
+			continue
+		}
+
+		// caught up
+		if len(claimed) > 0 {
+			poll = pollMin
+		} else {
+			poll = min(poll*2, pollMax)
+		}
+		d := poll
+		next, err := w.Repo.NextRunAt()
+		if err != nil {
+			log.Printf("worker next run error: %v\n", err)
+		} else if !next.IsZero() {
+			// floor: due rows another worker is claiming right now
+			d = min(d, max(time.Until(next), 50*time.Millisecond))
+		}
+		sleep(d)
+	}
+}
+
+// listen forwards the run_at of NOTIFY'd jobs. After a reconnect it sends
+// now, as notifications may have been lost. A failed LISTEN is retried
+// with backoff until ctx is done.
+func (w *Worker) listen(ctx context.Context, out chan<- time.Time) {
+	backoff := time.Second
+	for {
+		err := w.listenOnce(ctx, out)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("worker listen error: %v (retry in %s)\n", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+// listenOnce returns when ctx is done, or with the error of LISTEN.
+func (w *Worker) listenOnce(ctx context.Context, out chan<- time.Time) error {
+	l := pq.NewListener(w.DSN, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("worker listener: %v\n", err)
+		}
+	})
+	defer l.Close()
+	// Listen blocks while the database is down; closing unblocks it
+	stop := context.AfterFunc(ctx, func() { l.Close() })
+	defer stop()
+
+	if err := l.Listen(JobsChannel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case nt := <-l.Notify:
+			at := time.Now()
+			if nt != nil {
+				if ms, err := strconv.ParseInt(nt.Extra, 10, 64); err == nil {
+					at = time.UnixMilli(ms)
+				}
+			}
+			select {
+			case out <- at:
+			default: // Run is busy and rechecks NextRunAt anyway
+			}
+		case <-time.After(90 * time.Second):
+			go func() { _ = l.Ping() }()
+		}
+	}
+}
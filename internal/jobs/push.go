@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"tell/internal/netguard"
+	"tell/internal/webpush"
+
+	"gorm.io/gorm"
+)
+
+// PushNotifier is the "push" channel: one Web Push message per browser
+// subscription of the user. Subscriptions the push service reports gone
+// are deleted.
+type PushNotifier struct {
+	DB     *gorm.DB
+	Client *webpush.Client
+}
+
+// pushBody is what the service worker receives.
+type pushBody struct {
+	Kind   string    `json:"kind"`
+	Title  string    `json:"title"`
+	Body   string    `json:"body"`
+	MemoID uint64    `json:"memo_id,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// maxPushBody caps the text shown in the notification; pushPayload then
+// makes sure the JSON fits webpush.MaxPayload.
+const maxPushBody = 2048
+
+func (n *PushNotifier) Send(ctx context.Context, m Message) error {
+	db := n.DB.WithContext(ctx)
+
+	var subs []webpush.Subscription
+	if err := db.Where("user_id = ?", m.UserID).Find(&subs).Error; err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := pushPayload(pushBody{Kind: m.Kind, Title: m.Title, Body: truncate(m.Body, maxPushBody), MemoID: m.MemoID, At: m.At})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	delivered := 0
+	for _, sub := range subs {
+		err := n.Client.Send(ctx, sub, payload)
+		switch {
+		case err == nil:
+			delivered++
+			now := time.Now()
+			_ = db.Model(&webpush.Subscription{}).Where("id = ?", sub.ID).Update("last_used_at", now).Error
+		case errors.Is(err, webpush.ErrPayloadTooLarge):
+			return Permanent(err)
+		case errors.Is(err, webpush.ErrGone), errors.Is(err, netguard.ErrBlocked):
+			log.Printf("[PUSH] pruning subscription %d of user %d", sub.ID, m.UserID)
+			if err := db.Delete(&webpush.Subscription{}, sub.ID).Error; err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			errs = append(errs, fmt.Errorf("subscription %d: %w", sub.ID, err))
+		}
+	}
+
+	// one working device is enough; retrying would re-notify it
+	if delivered == 0 && len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// pushPayload marshals b, shortening Body and then Title until the JSON
+// fits webpush.MaxPayload. Escaping can grow a byte to six ("<" is
+// \u003c), so the cut is measured on the marshalled size.
+func pushPayload(b pushBody) ([]byte, error) {
+	for {
+		payload, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		over := len(payload) - webpush.MaxPayload
+		if over <= 0 {
+			return payload, nil
+		}
+		switch {
+		case b.Body != "":
+			b.Body = truncate(b.Body, len(b.Body)-over-len("…"))
+		case b.Title != "":
+			b.Title = truncate(b.Title, len(b.Title)-over-len("…"))
+		default:
+			return nil, Permanent(webpush.ErrPayloadTooLarge)
+		}
+	}
+}
+
+// truncate cuts s to at most n bytes on a rune boundary and marks the cut
+// with "…"; nothing is left when n <= 0.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	s = s[:n]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "…"
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"tell/internal/webpush"
+)
+
+func TestPushNotifierPrunesGone(t *testing.T) {
+	db := testDB(t)
+	uid := testUser()
+
+	_, priv, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vapid, err := webpush.NewVAPID(priv, "mailto:ops@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last path segment is the status the stand-in push service answers
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var code int
+		fmt.Sscan(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], &code)
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	ids := map[int]uint64{}
+	for _, code := range []int{http.StatusCreated, http.StatusNotFound, http.StatusGone} {
+		ua, err := ecdh.P256().GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		auth := make([]byte, 16)
+		rand.Read(auth)
+		sub := webpush.Subscription{
+			UserID:   uid,
+			Endpoint: fmt.Sprintf("%s/push/%d/%d", srv.URL, uid, code),
+			P256dh:   base64.RawURLEncoding.EncodeToString(ua.PublicKey().Bytes()),
+			Auth:     base64.RawURLEncoding.EncodeToString(auth),
+		}
+		if err := db.Create(&sub).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[code] = sub.ID
+	}
+	t.Cleanup(func() { db.Where("user_id = ?", uid).Delete(&webpush.Subscription{}) })
+
+	n := &PushNotifier{DB: db, Client: &webpush.Client{VAPID: vapid, HTTP: srv.Client()}}
+	if err := n.Send(context.Background(), Message{UserID: uid, Kind: "reminder", Title: "hi", At: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	var left []webpush.Subscription
+	if err := db.Where("user_id = ?", uid).Find(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].ID != ids[http.StatusCreated] {
+		t.Fatalf("left %+v, want only subscription %d", left, ids[http.StatusCreated])
+	}
+	if left[0].LastUsedAt == nil {
+		t.Fatal("last_used_at not set on delivery")
+	}
+}
+
+func TestPushPayloadFits(t *testing.T) {
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for name, b := range map[string]pushBody{
+		"short":           {Kind: "reminder", Title: "hi", Body: "beli susu", At: at},
+		"escaped body":    {Kind: "reminder", Title: "hi", Body: strings.Repeat("<&>", maxPushBody/3), At: at},
+		"multibyte body":  {Kind: "reminder", Title: "hi", Body: strings.Repeat("ä<", 1500), At: at},
+		"escaped title":   {Kind: "reminder", Title: strings.Repeat("<", 4000), Body: "x", At: at},
+		"everything long": {Kind: "reminder", Title: strings.Repeat("é", 3000), Body: strings.Repeat("&", 2000), At: at},
+	} {
+		payload, err := pushPayload(b)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(payload) > webpush.MaxPayload {
+			t.Fatalf("%s: %d bytes", name, len(payload))
+		}
+		var got pushBody
+		if err := json.Unmarshal(payload, &got); err != nil || !utf8.ValidString(got.Body) || !utf8.ValidString(got.Title) {
+			t.Fatalf("%s: %q (%v)", name, payload, err)
+		}
+		if name == "short" && got.Body != b.Body {
+			t.Fatalf("short body changed to %q", got.Body)
+		}
+	}
+
+	_, err := pushPayload(pushBody{Kind: strings.Repeat("k", webpush.MaxPayload), At: at})
+	if !IsPermanent(err) || !errors.Is(err, webpush.ErrPayloadTooLarge) {
+		t.Fatalf("oversized kind: %v", err)
+	}
+}
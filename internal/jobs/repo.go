@@ -2,6 +2,7 @@ package jobs
 
 import (
 	"encoding/json"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
@@ -25,17 +26,36 @@ func (r *Repo) EnqueueReminder(userID uint64, memoID uint64, runAt time.Time) er
 	return r.DB.Create(&j).Error
 }
 
-// Claim one due job atomically using SKIP LOCKED.
-// Works on Postgres.
-func (r *Repo) Claim(workerID string) (*Job, error) {
-	var job Job
+// EnqueueSnapshotBackfill asks the worker to write missing snapshots for a
+// memo, or for all of the user's memos when memoID is 0.
+func (r *Repo) EnqueueSnapshotBackfill(userID uint64, memoID uint64) error {
+	payload, _ := json.Marshal(map[string]any{
+		"memo_id": memoID,
+	})
+	j := Job{
+		UserID:      userID,
+		Type:        "SNAPSHOT_BACKFILL",
+		Payload:     payload,
+		RunAt:       time.Now(),
+		Status:      "PENDING",
+		MaxAttempts: 5,
+	}
+	return r.DB.Create(&j).Error
+}
+
+// Claim claims up to limit due jobs atomically using SKIP LOCKED, oldest
+// run_at first. Jobs RUNNING for longer than stuckAfter (their worker died)
+// are requeued first. Works on Postgres.
+func (r *Repo) Claim(workerID string, limit int, stuckAfter time.Duration) ([]Job, error) {
+	var claimed []Job
 	err := r.DB.Transaction(func(tx *gorm.DB) error {
-		// requeue stuck RUNNING jobs (optional safety)
-		tx.Exec(`
+		if err := tx.Exec(`
 update jobs
 set status='PENDING', locked_by=null, locked_at=null, updated_at=now()
-where status='RUNNING' and locked_at is not null and locked_at < now() - interval '5 minutes'
-`)
+where status='RUNNING' and locked_at is not null and locked_at < now() - ? * interval '1 second'
+`, stuckAfter.Seconds()).Error; err != nil {
+			return err
+		}
 
 		// claim
 		// FOR UPDATE SKIP LOCKED ensures no double-claim
@@ -46,35 +66,68 @@ with cte as (
   where status='PENDING' and run_at <= now()
   order by run_at asc
   for update skip locked
-  limit 1
+  limit ?
 )
 update jobs
 set status='RUNNING', locked_by=?, locked_at=now(), updated_at=now()
 where id in (select id from cte)
 returning *;
-`, workerID)
+`, limit, workerID)
 
-		return q.Scan(&job).Error
+		return q.Scan(&claimed).Error
 	})
 	if err != nil {
 		return nil, err
 	}
-	if job.ID == 0 {
-		return nil, nil
+	// RETURNING does not keep the CTE order
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].RunAt.Before(claimed[j].RunAt) })
+	return claimed, nil
+}
+
+// NextRunAt is the run_at of the earliest PENDING job, or zero when there
+// is none.
+func (r *Repo) NextRunAt() (time.Time, error) {
+	var next *time.Time
+	if err := r.DB.Raw(`select min(run_at) from jobs where status='PENDING'`).Scan(&next).Error; err != nil {
+		return time.Time{}, err
+	}
+	if next == nil {
+		return time.Time{}, nil
 	}
-	return &job, nil
+	return *next, nil
 }
 
 func (r *Repo) MarkDone(id uint64) error {
 	return r.DB.Exec(`update jobs set status='DONE', updated_at=now() where id=?`, id).Error
 }
 
+// MarkDoneWith is MarkDone recording what the job ended up doing.
+func (r *Repo) MarkDoneWith(id uint64, result string) error {
+	return r.DB.Exec(`update jobs set status='DONE', result=?, updated_at=now() where id=?`, result, id).Error
+}
+
 func (r *Repo) MarkFailed(id uint64, errMsg string) error {
 	return r.DB.Exec(`update jobs set status='FAILED', last_error=?, updated_at=now() where id=?`, errMsg, id).Error
 }
 
+// Reschedule puts a claimed job back to PENDING at runAt without counting
+// an attempt (used to defer, not to retry).
+func (r *Repo) Reschedule(id uint64, runAt time.Time) error {
+	if err := r.DB.Exec(`
+update jobs
+set status='PENDING',
+    run_at=?,
+    locked_by=null,
+    locked_at=null,
+    updated_at=now()
+where id=?`, runAt, id).Error; err != nil {
+		return err
+	}
+	return notifyJob(r.DB, runAt)
+}
+
 func (r *Repo) RetryLater(id uint64, attempts int, runAt time.Time, errMsg string) error {
-	return r.DB.Exec(`
+	if err := r.DB.Exec(`
 update jobs
 set status='PENDING',
     attempts=?,
@@ -83,5 +136,8 @@ set status='PENDING',
     locked_at=null,
     last_error=?,
     updated_at=now()
-where id=?`, attempts, runAt, errMsg, id).Error
+where id=?`, attempts, runAt, errMsg, id).Error; err != nil {
+		return err
+	}
+	return notifyJob(r.DB, runAt)
 }
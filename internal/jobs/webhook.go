@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tell/internal/netguard"
+	"tell/internal/webhook"
+
+	"gorm.io/gorm"
+)
+
+// webhookClient refuses internal addresses and redirects: webhook URLs
+// are user input.
+var webhookClient = netguard.NewClient(10 * time.Second)
+
+type webhookPayload struct {
+	SubscriptionID uint64          `json:"subscription_id"`
+	EventID        uint64          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Body           json.RawMessage `json:"body"`
+}
+
+func (w *Worker) handleWebhook(ctx context.Context, job *Job, p webhookPayload) (string, error) {
+	var sub webhook.Subscription
+	if err := w.DB.WithContext(ctx).Where("id=? AND user_id=?", p.SubscriptionID, job.UserID).First(&sub).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "skipped:deleted", nil // subscription deleted
+		}
+		return "", errors.New("db read error")
+	}
+	if !sub.Active {
+		return "skipped:inactive", nil
+	}
+
+	ts := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(p.Body))
+	if err != nil {
+		w.recordDelivery(job, p.SubscriptionID, p.EventID, 0, 0, err.Error())
+		return "", Permanent(errors.New("bad url"))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tell-webhooks/1")
+	req.Header.Set("X-Tell-Event", p.EventType)
+	req.Header.Set("X-Tell-Delivery", strconv.FormatUint(job.ID, 10))
+	req.Header.Set("X-Tell-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("X-Tell-Signature", webhook.Sign(sub.Secret, ts, p.Body))
+
+	start := time.Now()
+	resp, err := webhookClient.Do(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		w.recordDelivery(job, p.SubscriptionID, p.EventID, 0, elapsed, err.Error())
+		if errors.Is(err, netguard.ErrBlocked) {
+			return "", Permanent(err)
+		}
+		return "", err
+	}
+	_ = resp.Body.Close()
+
+	msg := fmt.Sprintf("http %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		w.recordDelivery(job, p.SubscriptionID, p.EventID, resp.StatusCode, elapsed, "")
+		return "delivered", nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests:
+		w.recordDelivery(job, p.SubscriptionID, p.EventID, resp.StatusCode, elapsed, msg)
+		return "", errors.New(msg)
+	default:
+		// other 4xx: the receiver rejected it, retrying won't help
+		w.recordDelivery(job, p.SubscriptionID, p.EventID, resp.StatusCode, elapsed, msg)
+		return "", Permanent(errors.New(msg))
+	}
+}
+
+func (w *Worker) recordDelivery(job *Job, subID, eventID uint64, status int, elapsed time.Duration, errMsg string) {
+	d := webhook.Delivery{
+		SubscriptionID: subID,
+		UserID:         job.UserID,
+		JobID:          job.ID,
+		EventID:        eventID,
+		Attempt:        job.Attempts + 1,
+		StatusCode:     status,
+		DurationMs:     elapsed.Milliseconds(),
+	}
+	if errMsg != "" {
+		d.Error = &errMsg
+	}
+	if err := w.DB.Create(&d).Error; err != nil {
+		log.Printf("webhook delivery record error: %v\n", err)
+	}
+}
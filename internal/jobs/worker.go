@@ -2,11 +2,13 @@ package jobs
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"math"
 	"time"
 
+	"tell/internal/auth"
+
 	"gorm.io/gorm"
 )
 
@@ -14,6 +16,41 @@ type Worker struct {
 	ID   string
 	Repo *Repo
 	DB   *gorm.DB
+
+	Snapshots Snapshotter
+	Notifiers *Notifiers // reminder channels; nil = log only
+	Reminders ReminderRecorder
+
+	// Handlers runs job types other than the built-in ones, or replaces
+	// them; nil = built-ins only.
+	Handlers *Registry
+
+	Concurrency int           // jobs handled at once; 0 = 1
+	BatchSize   int           // jobs claimed per round trip; 0 = Concurrency
+	DSN         string        // LISTENs on JobsChannel when set
+	PollMin     time.Duration // 0 = 800ms
+	PollMax     time.Duration // 0 = 30s with DSN, 5s without
+}
+
+// Snapshotter backfills memo snapshots (memo.Service). It is an interface
+// because memo already imports jobs.
+type Snapshotter interface {
+	BackfillSnapshots(ctx context.Context, userID, memoID uint64) error
+}
+
+// ReminderFired is what the worker reports after dispatching a reminder.
+type ReminderFired struct {
+	UserID     uint64
+	MemoID     uint64
+	ReminderID string
+	JobID      uint64
+	FiredAt    time.Time
+	Channels   []string
+}
+
+// ReminderRecorder appends REMINDER_FIRED to the memo log (memo.Service).
+type ReminderRecorder interface {
+	RecordReminderFired(ctx context.Context, f ReminderFired) error
 }
 
 type memoProjection struct {
@@ -26,78 +63,184 @@ type memoProjection struct {
 
 func (memoProjection) TableName() string { return "memo_projections" }
 
-func (w *Worker) Run(ctx context.Context) {
-	ticker := time.NewTicker(800 * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			job, err := w.Repo.Claim(w.ID)
-			if err != nil {
-				log.Printf("worker claim error: %v\n", err)
-				continue
-			}
-			if job == nil {
-				continue
-			}
-			w.handle(job)
+// memoReminder mirrors the columns of memo.MemoReminder the worker needs.
+type memoReminder struct {
+	MemoID     uint64     `gorm:"column:memo_id"`
+	ReminderID string     `gorm:"column:reminder_id"`
+	RemindAt   *time.Time `gorm:"column:remind_at"`
+}
+
+func (memoReminder) TableName() string { return "memo_reminders" }
+
+// registerBuiltins adds the handlers of the job types this package
+// enqueues, unless the caller registered its own. Their attempts come from
+// the job row (max_attempts), so it can be tuned per job.
+func (w *Worker) registerBuiltins() {
+	builtins := map[string]registration{
+		"REMINDER_DISPATCH": {Typed(w.handleReminder), Policy{Timeout: time.Minute}},
+		"SNAPSHOT_BACKFILL": {Typed(w.handleSnapshotBackfill), Policy{Timeout: 4 * time.Minute}},
+		"WEBHOOK_DELIVERY":  {Typed(w.handleWebhook), Policy{Timeout: 30 * time.Second}},
+	}
+	for typ, reg := range builtins {
+		if _, ok := w.Handlers.lookup(typ); !ok {
+			w.Handlers.Register(typ, reg.handler, reg.policy)
 		}
 	}
 }
 
-func (w *Worker) handle(job *Job) {
-	switch job.Type {
-	case "REMINDER_DISPATCH":
-		w.handleReminder(job)
-	default:
+// handle runs job and settles it: done with the handler's result, deferred,
+// retried with backoff, or failed when the error is permanent or attempts
+// run out. A job cut short by ctx (the pool shutting down) goes back to
+// PENDING without counting an attempt.
+func (w *Worker) handle(ctx context.Context, job *Job) {
+	reg, ok := w.Handlers.lookup(job.Type)
+	if !ok {
 		_ = w.Repo.MarkFailed(job.ID, "unknown job type")
+		return
+	}
+
+	result, err := w.run(ctx, reg, job)
+
+	var d *deferError
+	switch {
+	case err == nil && result == "":
+		_ = w.Repo.MarkDone(job.ID)
+	case err == nil:
+		_ = w.Repo.MarkDoneWith(job.ID, result)
+	case errors.As(err, &d):
+		if err := w.Repo.Reschedule(job.ID, d.until); err != nil {
+			w.retry(job, reg.policy, "reschedule: "+err.Error())
+		}
+	case ctx.Err() != nil:
+		if err := w.Repo.Reschedule(job.ID, time.Now()); err != nil {
+			log.Printf("job %d: requeue on shutdown: %v\n", job.ID, err)
+		}
+	case IsPermanent(err):
+		_ = w.Repo.MarkFailed(job.ID, err.Error())
+	default:
+		w.retry(job, reg.policy, err.Error())
 	}
 }
 
-func (w *Worker) handleReminder(job *Job) {
-	type payload struct {
-		MemoID uint64 `json:"memo_id"`
+// run calls the handler under the policy timeout; a panic is a retryable
+// error.
+func (w *Worker) run(ctx context.Context, reg registration, job *Job) (result string, err error) {
+	if reg.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, reg.policy.Timeout)
+		defer cancel()
 	}
-	var p payload
-	if err := json.Unmarshal(job.Payload, &p); err != nil {
-		_ = w.Repo.MarkFailed(job.ID, "bad payload")
-		return
+	defer func() {
+		if v := recover(); v != nil {
+			log.Printf("job %d (%s) panicked: %v\n", job.ID, job.Type, v)
+			result, err = "", fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return reg.handler.Handle(ctx, job)
+}
+
+// Results of REMINDER_DISPATCH jobs, kept in Job.Result for the history.
+const (
+	ResultFired           = "fired"
+	ResultSkippedArchived = "skipped:archived"
+	ResultSkippedDeleted  = "skipped:deleted"
+	ResultSkippedCleared  = "skipped:cleared"
+)
+
+type reminderPayload struct {
+	MemoID     uint64 `json:"memo_id"`
+	ReminderID string `json:"reminder_id"` // empty in jobs from before multiple reminders
+}
+
+func (w *Worker) handleReminder(ctx context.Context, job *Job, p reminderPayload) (string, error) {
+	if p.ReminderID == "" {
+		p.ReminderID = "default"
 	}
+	db := w.DB.WithContext(ctx)
 
 	var proj memoProjection
-	if err := w.DB.
+	if err := db.
 		Where("memo_id=? AND user_id=?", p.MemoID, job.UserID).
 		First(&proj).Error; err != nil {
 
 		if err == gorm.ErrRecordNotFound {
-			_ = w.Repo.MarkDone(job.ID)
-			return
+			return ResultSkippedDeleted, nil
 		}
-		w.retry(job, "db read error")
-		return
+		return "", errors.New("db read error")
 	}
 
-	if proj.Archived || proj.RemindAt == nil {
-		_ = w.Repo.MarkDone(job.ID)
-		return
+	if proj.Archived {
+		return ResultSkippedArchived, nil
+	}
+
+	var rem memoReminder
+	if err := db.
+		Where("memo_id=? AND reminder_id=?", p.MemoID, p.ReminderID).
+		First(&rem).Error; err != nil {
+
+		if err == gorm.ErrRecordNotFound {
+			return ResultSkippedCleared, nil
+		}
+		return "", errors.New("db read error")
+	}
+	if rem.RemindAt == nil {
+		return ResultSkippedCleared, nil
 	}
 
-	log.Printf("[REMINDER] user=%d memo=%d content=%q\n", job.UserID, proj.MemoID, proj.Content)
-	_ = w.Repo.MarkDone(job.ID)
+	// defer to the end of the user's quiet hours
+	settings, err := auth.LoadSettings(db, job.UserID)
+	if err != nil {
+		return "", fmt.Errorf("load settings: %w", err)
+	}
+	if until, quiet := settings.QuietUntil(time.Now()); quiet {
+		return "", Defer(until)
+	}
+
+	msg := Message{
+		UserID: job.UserID,
+		MemoID: proj.MemoID,
+		Kind:   "reminder",
+		Title:  "Reminder",
+		Body:   proj.Content,
+		At:     time.Now(),
+	}
+	// retries only resend the channels that failed
+	sent, err := w.dispatch(ctx, job, msg)
+	if err != nil {
+		return "", err
+	}
+
+	if w.Reminders != nil {
+		if err := w.Reminders.RecordReminderFired(ctx, ReminderFired{
+			UserID:     job.UserID,
+			MemoID:     proj.MemoID,
+			ReminderID: p.ReminderID,
+			JobID:      job.ID,
+			FiredAt:    msg.At,
+			Channels:   sent,
+		}); err != nil {
+			return "", fmt.Errorf("record fired: %w", err)
+		}
+	}
+	return ResultFired, nil
 }
 
-func (w *Worker) retry(job *Job, errMsg string) {
+type snapshotPayload struct {
+	MemoID uint64 `json:"memo_id"` // 0 = all memos of the user
+}
+
+func (w *Worker) handleSnapshotBackfill(ctx context.Context, job *Job, p snapshotPayload) (string, error) {
+	if w.Snapshots == nil {
+		return "", Permanent(errors.New("no snapshotter configured"))
+	}
+	return "", w.Snapshots.BackfillSnapshots(ctx, job.UserID, p.MemoID)
+}
+
+func (w *Worker) retry(job *Job, p Policy, errMsg string) {
 	attempts := job.Attempts + 1
-	if attempts >= job.MaxAttempts {
+	if attempts >= p.maxAttempts(job) {
 		_ = w.Repo.MarkFailed(job.ID, errMsg)
 		return
 	}
-
-	sec := math.Min(math.Pow(2, float64(attempts)), 600)
-	next := time.Now().Add(time.Duration(sec) * time.Second)
-
-	_ = w.Repo.RetryLater(job.ID, attempts, next, errMsg)
+	_ = w.Repo.RetryLater(job.ID, attempts, time.Now().Add(p.backoff(attempts)), errMsg)
 }
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClaimExpiry(t *testing.T) {
+	noop := HandlerFunc(func(context.Context, *Job) (string, error) { return "", nil })
+
+	r := NewRegistry()
+	if got := r.claimExpiry(); got != untimedExpiry+claimMargin {
+		t.Fatalf("empty registry: %s", got)
+	}
+	r.Register("A", noop, Policy{Timeout: 30 * time.Second})
+	r.Register("B", noop, Policy{Timeout: 10 * time.Minute})
+	if got := r.claimExpiry(); got != 10*time.Minute+claimMargin {
+		t.Fatalf("longest timeout: %s", got)
+	}
+	r.Register("B", noop, Policy{})
+	if got := r.claimExpiry(); got != untimedExpiry+claimMargin {
+		t.Fatalf("untimed type: %s", got)
+	}
+}
+
+func TestHandleSettles(t *testing.T) {
+	db := testDB(t)
+	uid := testUser()
+	w := &Worker{ID: "test", Repo: &Repo{DB: db}, DB: db, Handlers: NewRegistry()}
+
+	later := time.Now().Add(time.Hour).Truncate(time.Second)
+	failing := errors.New("boom")
+	w.Handlers.Register("PERMANENT", HandlerFunc(func(context.Context, *Job) (string, error) {
+		return "", Permanent(failing)
+	}), Policy{})
+	w.Handlers.Register("DEFER", HandlerFunc(func(context.Context, *Job) (string, error) {
+		return "", Defer(later)
+	}), Policy{})
+	w.Handlers.Register("SLOW", HandlerFunc(func(ctx context.Context, _ *Job) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}), Policy{Timeout: 20 * time.Millisecond})
+	w.Handlers.Register("FAILING", HandlerFunc(func(context.Context, *Job) (string, error) {
+		return "", failing
+	}), Policy{})
+
+	settle := func(ctx context.Context, typ string, attempts, maxAttempts int) Job {
+		t.Helper()
+		j := Job{UserID: uid, Type: typ, Payload: []byte(`{}`), RunAt: time.Now(), Status: "RUNNING", Attempts: attempts, MaxAttempts: maxAttempts}
+		if err := db.Create(&j).Error; err != nil {
+			t.Fatal(err)
+		}
+		w.handle(ctx, &j)
+		var got Job
+		if err := db.First(&got, j.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	ctx := context.Background()
+
+	if j := settle(ctx, "PERMANENT", 0, 8); j.Status != "FAILED" || j.Attempts != 0 {
+		t.Fatalf("permanent: %s after %d attempts", j.Status, j.Attempts)
+	}
+	if j := settle(ctx, "DEFER", 2, 8); j.Status != "PENDING" || j.Attempts != 2 || !j.RunAt.Equal(later) {
+		t.Fatalf("defer: %s, %d attempts, run_at %s", j.Status, j.Attempts, j.RunAt)
+	}
+
+	start := time.Now()
+	j := settle(ctx, "SLOW", 0, 8)
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("timeout not enforced")
+	}
+	if j.Status != "PENDING" || j.Attempts != 1 || j.LastError == nil || !strings.Contains(*j.LastError, "deadline") {
+		t.Fatalf("timeout: %s, %d attempts, %v", j.Status, j.Attempts, j.LastError)
+	}
+
+	// the policy has no MaxAttempts, so the row's is used
+	if j := settle(ctx, "FAILING", 0, 2); j.Status != "PENDING" || j.Attempts != 1 {
+		t.Fatalf("first failure: %s after %d attempts", j.Status, j.Attempts)
+	}
+	if j := settle(ctx, "FAILING", 1, 2); j.Status != "FAILED" {
+		t.Fatalf("last attempt: %s", j.Status)
+	}
+
+	// a shutdown is not the job's fault
+	stopped, cancel := context.WithCancel(ctx)
+	cancel()
+	if j := settle(stopped, "SLOW", 3, 4); j.Status != "PENDING" || j.Attempts != 3 {
+		t.Fatalf("shutdown: %s, %d attempts", j.Status, j.Attempts)
+	}
+}
+
+func TestClaimRequeuesStuck(t *testing.T) {
+	db := testDB(t)
+	uid := testUser()
+	repo := &Repo{DB: db}
+
+	// run_at far back so other due rows in the database do not crowd it out
+	epoch := time.Unix(1, 0)
+	old := time.Now().Add(-time.Hour)
+	fresh := time.Now()
+	owner := "gone"
+	stuck := Job{UserID: uid, Type: "X", Payload: []byte(`{}`), RunAt: epoch, Status: "RUNNING", LockedBy: &owner, LockedAt: &old}
+	busy := Job{UserID: uid, Type: "X", Payload: []byte(`{}`), RunAt: epoch, Status: "RUNNING", LockedBy: &owner, LockedAt: &fresh}
+	if err := db.Create(&stuck).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&busy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Claim("test", 100, 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var got []Job
+	if err := db.Where("user_id = ?", uid).Order("id").Find(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].LockedBy == nil || *got[0].LockedBy != "test" || *got[1].LockedBy != owner {
+		t.Fatalf("after claim: %+v", got)
+	}
+}
@@ -0,0 +1,39 @@
+package memo
+
+import (
+	"math/rand/v2"
+	"os"
+	"testing"
+
+	"tell/internal/auth"
+	"tell/internal/jobs"
+	"tell/internal/webhook"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to TELL_TEST_DATABASE_URL (a scratch Postgres database)
+// or skips the test.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TELL_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TELL_TEST_DATABASE_URL not set")
+	}
+	gdb, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.AutoMigrate(
+		&Memo{}, &MemoEvent{}, &MemoProjection{}, &MemoReminder{}, &Tag{}, &MemoTag{}, &MemoSnapshot{},
+		&jobs.Job{}, &webhook.Subscription{}, &auth.UserSettings{},
+	); err != nil {
+		t.Fatal(err)
+	}
+	return gdb
+}
+
+// testUser is a user id no other test run uses.
+func testUser() uint64 { return 1<<40 + rand.Uint64N(1<<30) }
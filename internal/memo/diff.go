@@ -0,0 +1,327 @@
+package memo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRange = errors.New("invalid range")
+
+// DiffOp is one run of a token diff.
+type DiffOp struct {
+	Op   string // equal / insert / delete
+	Text string
+}
+
+type BoolChange struct {
+	From bool `json:"from"`
+	To   bool `json:"to"`
+}
+
+type TimeChange struct {
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+}
+
+// MemoDiff describes how a memo changed between two versions (event ids).
+type MemoDiff struct {
+	From uint64
+	To   uint64
+
+	Unified string   // line-level unified diff of content
+	Words   []DiffOp // word-level diff of content
+
+	TagsAdded   []string
+	TagsRemoved []string
+
+	Archived *BoolChange // nil when unchanged
+	RemindAt *TimeChange // nil when unchanged
+
+	Reminders []ReminderChange // one per added, removed or changed reminder
+}
+
+// ReminderChange is one reminder's before/after; From is nil when it was
+// added, To when it was removed.
+type ReminderChange struct {
+	ReminderID string
+	From       *MemoReminder
+	To         *MemoReminder
+}
+
+// Diff folds the memo at both versions and compares the states.
+// Both ids must be events of this memo.
+func (s *Service) Diff(ctx context.Context, userID, memoID, from, to uint64) (MemoDiff, error) {
+	if from > to {
+		return MemoDiff{}, ErrInvalidRange
+	}
+	db := s.DB.WithContext(ctx)
+
+	var n int64
+	if err := db.Model(&MemoEvent{}).
+		Where("memo_id=? AND user_id=? AND id in ?", memoID, userID, []uint64{from, to}).
+		Count(&n).Error; err != nil {
+		return MemoDiff{}, err
+	}
+	want := int64(2)
+	if from == to {
+		want = 1
+	}
+	if n != want {
+		return MemoDiff{}, ErrNotFound
+	}
+
+	a, _, err := foldAt(db, memoID, userID, AsOf{Version: from})
+	if err != nil {
+		return MemoDiff{}, err
+	}
+	evs, err := loadEvents(db, memoID, userID, from, AsOf{Version: to})
+	if err != nil {
+		return MemoDiff{}, err
+	}
+	b := a
+	for _, ev := range evs {
+		if err := Apply(&b, ev); err != nil {
+			return MemoDiff{}, err
+		}
+	}
+
+	return diffStates(from, to, a, b), nil
+}
+
+func diffStates(from, to uint64, a, b MemoProjection) MemoDiff {
+	d := MemoDiff{
+		From:    from,
+		To:      to,
+		Unified: UnifiedDiff(a.Content, b.Content, fmt.Sprintf("v%d", from), fmt.Sprintf("v%d", to), 3),
+		Words:   DiffWords(a.Content, b.Content),
+	}
+
+	had := map[string]bool{}
+	for _, t := range a.Tags {
+		had[t] = true
+	}
+	has := map[string]bool{}
+	for _, t := range b.Tags {
+		has[t] = true
+		if !had[t] {
+			d.TagsAdded = append(d.TagsAdded, t)
+		}
+	}
+	for _, t := range a.Tags {
+		if !has[t] {
+			d.TagsRemoved = append(d.TagsRemoved, t)
+		}
+	}
+
+	if a.Archived != b.Archived {
+		d.Archived = &BoolChange{From: a.Archived, To: b.Archived}
+	}
+	if !sameTime(a.RemindAt, b.RemindAt) {
+		d.RemindAt = &TimeChange{From: a.RemindAt, To: b.RemindAt}
+	}
+	d.Reminders = diffReminders(a.Reminders, b.Reminders)
+	return d
+}
+
+// diffReminders pairs reminders by ReminderID, in a's order then b's new ones.
+func diffReminders(a, b []MemoReminder) []ReminderChange {
+	byID := make(map[string]int, len(b))
+	for i, r := range b {
+		byID[r.ReminderID] = i
+	}
+	var out []ReminderChange
+	seen := make(map[string]bool, len(a))
+	for i := range a {
+		x := &a[i]
+		seen[x.ReminderID] = true
+		j, ok := byID[x.ReminderID]
+		if !ok {
+			out = append(out, ReminderChange{ReminderID: x.ReminderID, From: x})
+			continue
+		}
+		if y := &b[j]; !sameReminder(*x, *y) {
+			out = append(out, ReminderChange{ReminderID: x.ReminderID, From: x, To: y})
+		}
+	}
+	for i := range b {
+		if y := &b[i]; !seen[y.ReminderID] {
+			out = append(out, ReminderChange{ReminderID: y.ReminderID, To: y})
+		}
+	}
+	return out
+}
+
+var wordRe = regexp.MustCompile(`\s+|\S+`)
+
+// DiffWords diffs on word and whitespace tokens, so joining the Text of
+// equal+delete ops gives a and equal+insert ops gives b.
+func DiffWords(a, b string) []DiffOp {
+	ops := diffTokens(wordRe.FindAllString(a, -1), wordRe.FindAllString(b, -1))
+
+	// merge adjacent runs of the same op
+	out := make([]DiffOp, 0, len(ops))
+	for _, op := range ops {
+		if n := len(out); n > 0 && out[n-1].Op == op.Op {
+			out[n-1].Text += op.Text
+			continue
+		}
+		out = append(out, op)
+	}
+	return out
+}
+
+// UnifiedDiff renders a line diff in unified format with ctx lines of context.
+// Empty when a == b.
+func UnifiedDiff(a, b, nameA, nameB string, ctx int) string {
+	if a == b {
+		return ""
+	}
+	ops := diffTokens(strings.Split(a, "\n"), strings.Split(b, "\n"))
+
+	var sb strings.Builder
+	sb.WriteString("--- " + nameA + "\n")
+	sb.WriteString("+++ " + nameB + "\n")
+
+	i := 0
+	for i < len(ops) {
+		// find next change
+		for i < len(ops) && ops[i].Op == "equal" {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := max(0, i-ctx)
+		end := i
+		// extend while the next change is within 2*ctx equal lines
+		for end < len(ops) {
+			if ops[end].Op != "equal" {
+				end++
+				continue
+			}
+			j := end
+			for j < len(ops) && ops[j].Op == "equal" {
+				j++
+			}
+			if j == len(ops) || j-end > 2*ctx {
+				end = min(len(ops), end+ctx)
+				break
+			}
+			end = j
+		}
+
+		// line numbers of hunk start
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:start] {
+			if op.Op != "insert" {
+				oldLine++
+			}
+			if op.Op != "delete" {
+				newLine++
+			}
+		}
+		oldN, newN := 0, 0
+		for _, op := range ops[start:end] {
+			if op.Op != "insert" {
+				oldN++
+			}
+			if op.Op != "delete" {
+				newN++
+			}
+		}
+		if oldN == 0 {
+			oldLine--
+		}
+		if newN == 0 {
+			newLine--
+		}
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldLine, oldN, newLine, newN)
+		for _, op := range ops[start:end] {
+			switch op.Op {
+			case "equal":
+				sb.WriteString(" ")
+			case "delete":
+				sb.WriteString("-")
+			case "insert":
+				sb.WriteString("+")
+			}
+			sb.WriteString(op.Text + "\n")
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// maxDiffCells caps the LCS table (4 bytes a cell, so 4MB); beyond it the
+// middle of the diff degrades to delete-all/insert-all.
+const maxDiffCells = 1 << 20
+
+// diffTokens is a plain LCS diff, one op per token.
+func diffTokens(a, b []string) []DiffOp {
+	// trim common prefix/suffix to keep the table small
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	out := make([]DiffOp, 0, len(a)+len(b))
+	for _, t := range a[:pre] {
+		out = append(out, DiffOp{Op: "equal", Text: t})
+	}
+
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	n, m := len(ma), len(mb)
+	if (n+1)*(m+1) > maxDiffCells {
+		for _, t := range ma {
+			out = append(out, DiffOp{Op: "delete", Text: t})
+		}
+		for _, t := range mb {
+			out = append(out, DiffOp{Op: "insert", Text: t})
+		}
+	} else {
+		// lcs(i, j) = LCS length of ma[i:], mb[j:]
+		w := m + 1
+		tab := make([]int32, (n+1)*w)
+		lcs := func(i, j int) int32 { return tab[i*w+j] }
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					tab[i*w+j] = lcs(i+1, j+1) + 1
+				} else {
+					tab[i*w+j] = max(lcs(i+1, j), lcs(i, j+1))
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && ma[i] == mb[j]:
+				out = append(out, DiffOp{Op: "equal", Text: ma[i]})
+				i++
+				j++
+			case i < n && (j == m || lcs(i+1, j) >= lcs(i, j+1)):
+				out = append(out, DiffOp{Op: "delete", Text: ma[i]})
+				i++
+			default:
+				out = append(out, DiffOp{Op: "insert", Text: mb[j]})
+				j++
+			}
+		}
+	}
+
+	for _, t := range a[len(a)-suf:] {
+		out = append(out, DiffOp{Op: "equal", Text: t})
+	}
+	return out
+}
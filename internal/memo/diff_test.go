@@ -0,0 +1,137 @@
+package memo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffWords(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []DiffOp
+	}{
+		{"cek mixer", "cek mixer #shift1", []DiffOp{{"equal", "cek mixer"}, {"insert", " #shift1"}}},
+		{"beli susu dan roti", "beli kopi dan roti", []DiffOp{{"equal", "beli "}, {"delete", "susu"}, {"insert", "kopi"}, {"equal", " dan roti"}}},
+		{"", "baru", []DiffOp{{"insert", "baru"}}},
+		{"sama", "sama", []DiffOp{{"equal", "sama"}}},
+	}
+	for _, tt := range tests {
+		got := DiffWords(tt.a, tt.b)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DiffWords(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if a, b := rebuild(got); a != tt.a || b != tt.b {
+			t.Errorf("DiffWords(%q, %q) rebuilds to %q, %q", tt.a, tt.b, a, b)
+		}
+	}
+}
+
+func rebuild(ops []DiffOp) (a, b string) {
+	for _, op := range ops {
+		if op.Op != "insert" {
+			a += op.Text
+		}
+		if op.Op != "delete" {
+			b += op.Text
+		}
+	}
+	return a, b
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	if got := UnifiedDiff("x", "x", "v1", "v2", 3); got != "" {
+		t.Fatalf("equal content: %q", got)
+	}
+	got := UnifiedDiff("a\nb\nc", "a\nB\nc", "v1", "v2", 1)
+	want := "--- v1\n+++ v2\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"
+	if got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDiffTokensCap(t *testing.T) {
+	// 2000x2000 distinct middle tokens is over maxDiffCells: the middle
+	// degrades to delete-all/insert-all, the shared ends stay equal.
+	n := 2000
+	a, b := []string{"head"}, []string{"head"}
+	for i := range n {
+		a = append(a, "a"+strings.Repeat("x", i%7))
+		b = append(b, "b"+strings.Repeat("x", i%7))
+	}
+	a, b = append(a, "tail"), append(b, "tail")
+
+	ops := diffTokens(a, b)
+	if len(ops) != 2*n+2 {
+		t.Fatalf("%d ops", len(ops))
+	}
+	if ops[0] != (DiffOp{"equal", "head"}) || ops[len(ops)-1] != (DiffOp{"equal", "tail"}) {
+		t.Fatalf("ends %v %v", ops[0], ops[len(ops)-1])
+	}
+	for i, op := range ops[1 : len(ops)-1] {
+		if want := map[bool]string{true: "delete", false: "insert"}[i < n]; op.Op != want {
+			t.Fatalf("op %d = %v, want %s", i, op, want)
+		}
+	}
+}
+
+func TestDiffStates(t *testing.T) {
+	at := func(h int) *time.Time {
+		v := time.Date(2026, 3, 1, h, 0, 0, 0, time.UTC)
+		return &v
+	}
+	a := MemoProjection{
+		Content: "rapat",
+		Tags:    []string{"kerja", "rutin"},
+		Reminders: []MemoReminder{
+			{ReminderID: "r1", RemindAt: at(9), Status: "PENDING"},
+			{ReminderID: "r2", RemindAt: at(10), Status: "PENDING", Rule: "FREQ=DAILY", Start: at(10)},
+			{ReminderID: "r3", RemindAt: at(11), Status: "PENDING"},
+		},
+		RemindAt: at(9),
+	}
+	b := MemoProjection{
+		Content:  "rapat #penting",
+		Tags:     []string{"kerja", "penting"},
+		Archived: true,
+		Reminders: []MemoReminder{
+			{ReminderID: "r1", RemindAt: at(9), Status: "PENDING"},
+			{ReminderID: "r2", RemindAt: at(10), Status: "FIRED", Rule: "FREQ=DAILY", Start: at(10), FiredCount: 1, LastFiredAt: at(10)},
+			{ReminderID: "r4", RemindAt: at(12), Status: "PENDING"},
+		},
+		RemindAt: at(9),
+	}
+
+	d := diffStates(1, 2, a, b)
+	if !reflect.DeepEqual(d.TagsAdded, []string{"penting"}) || !reflect.DeepEqual(d.TagsRemoved, []string{"rutin"}) {
+		t.Fatalf("tags +%v -%v", d.TagsAdded, d.TagsRemoved)
+	}
+	if d.Archived == nil || d.Archived.From || !d.Archived.To {
+		t.Fatalf("archived %+v", d.Archived)
+	}
+	// the summary is unchanged, the non-summary reminders are not
+	if d.RemindAt != nil {
+		t.Fatalf("remind_at %+v", d.RemindAt)
+	}
+
+	type change struct {
+		id       string
+		from, to bool
+	}
+	var got []change
+	for _, c := range d.Reminders {
+		got = append(got, change{c.ReminderID, c.From != nil, c.To != nil})
+	}
+	want := []change{{"r2", true, true}, {"r3", true, false}, {"r4", false, true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("reminders %v, want %v", got, want)
+	}
+	if c := d.Reminders[0]; c.From.Status != "PENDING" || c.To.Status != "FIRED" || c.To.FiredCount != 1 {
+		t.Fatalf("r2 %+v -> %+v", *c.From, *c.To)
+	}
+
+	if d := diffStates(1, 1, a, a); d.Unified != "" || d.Reminders != nil || d.Archived != nil {
+		t.Fatalf("self diff %+v", d)
+	}
+}
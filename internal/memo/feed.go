@@ -0,0 +1,28 @@
+package memo
+
+import "gorm.io/gorm"
+
+// eventsLockSpace namespaces the per-user advisory lock of lockUserEvents.
+const eventsLockSpace = 0x7e11
+
+// lockUserEvents serializes the user's event-writing transactions until
+// commit. Event ids come from a sequence when the row is inserted, so
+// without it a slow tx holding id N could commit after N+1 was already
+// served, and a reader whose cursor passed N would never see it. With it,
+// a user's ids are handed out in commit order and id cursors are safe.
+// It must be taken before any row lock, as a sync batch writes to several
+// memos in one tx.
+func lockUserEvents(tx *gorm.DB, userID uint64) error {
+	return tx.Exec(`select pg_advisory_xact_lock(?, ?)`, eventsLockSpace, int32(userID)).Error
+}
+
+// EventsAfter returns up to limit of the user's events with id > after, in
+// id order; the last id is the next cursor.
+func EventsAfter(db *gorm.DB, userID, after uint64, limit int) ([]MemoEvent, error) {
+	var evs []MemoEvent
+	err := db.Where("user_id = ? AND id > ?", userID, after).
+		Order("id asc").
+		Limit(limit).
+		Find(&evs).Error
+	return evs, err
+}
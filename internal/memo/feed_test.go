@@ -0,0 +1,86 @@
+package memo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Two appends for one user whose transactions would commit out of id
+// order: a reader must never move its cursor past the slow one's event.
+func TestEventsAfterCommitOrder(t *testing.T) {
+	db := testDB(t)
+	s := &Service{DB: db}
+	ctx := context.Background()
+	uid := testUser()
+
+	memoID, err := s.CreateMemo(ctx, uid, CreateMemoInput{Content: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	evs, err := EventsAfter(db, uid, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := evs[len(evs)-1].ID
+
+	// slow: takes its id, then holds the tx open
+	inserted, release, slowDone := make(chan uint64), make(chan struct{}), make(chan error, 1)
+	go func() {
+		slowDone <- db.Transaction(func(tx *gorm.DB) error {
+			if err := lockUserEvents(tx, uid); err != nil {
+				return err
+			}
+			ev, err := s.insertEvent(tx, memoID, uid, "UPDATED", UpdatedPayload{Content: "slow"}, nil)
+			if err != nil {
+				return err
+			}
+			inserted <- ev.ID
+			<-release
+			return nil
+		})
+	}()
+	slowID := <-inserted
+
+	// fast: a normal append started after the slow one took its id
+	content := "fast"
+	fastDone := make(chan error, 1)
+	go func() {
+		_, err := s.AppendEvent(ctx, AppendEventInput{MemoID: memoID, UserID: uid, Type: "UPDATED", Content: &content})
+		fastDone <- err
+	}()
+
+	// a reader polling meanwhile
+	var seen []uint64
+	read := func() {
+		evs, err := EventsAfter(db, uid, cursor, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ev := range evs {
+			seen = append(seen, ev.ID)
+			cursor = ev.ID
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	read()
+	if len(seen) != 0 {
+		t.Fatalf("events %v served while event %d is uncommitted", seen, slowID)
+	}
+
+	close(release)
+	if err := <-slowDone; err != nil {
+		t.Fatal(err)
+	}
+	read()
+	if err := <-fastDone; err != nil {
+		t.Fatal(err)
+	}
+	read()
+
+	if len(seen) != 2 || seen[0] != slowID || seen[1] <= slowID {
+		t.Fatalf("reader saw %v, want slow event %d then the fast one", seen, slowID)
+	}
+}
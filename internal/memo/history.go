@@ -0,0 +1,76 @@
+package memo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AsOf bounds a point-in-time read, either by version (event id) or by
+// wall-clock time. The zero value means "latest".
+type AsOf struct {
+	Version uint64
+	Time    *time.Time
+}
+
+// ParseAsOf accepts an event id or an RFC3339 timestamp. Event ids start at
+// 1; 0 is refused rather than read as the zero AsOf (latest).
+func ParseAsOf(s string) (AsOf, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		if v == 0 {
+			return AsOf{}, errors.New("as_of: event id 0")
+		}
+		return AsOf{Version: v}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return AsOf{}, err
+	}
+	return AsOf{Time: &t}, nil
+}
+
+// StateAt folds the memo's events up to at (from the nearest snapshot), using
+// the same Apply as the live projection. ErrNotFound if the memo is not the
+// user's or did not exist yet.
+func (s *Service) StateAt(ctx context.Context, userID, memoID uint64, at AsOf) (MemoProjection, error) {
+	db := s.DB.WithContext(ctx)
+
+	var m Memo
+	if err := db.Where("id=? AND user_id=?", memoID, userID).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return MemoProjection{}, ErrNotFound
+		}
+		return MemoProjection{}, err
+	}
+
+	p, ok, err := foldAt(db, memoID, userID, at)
+	if err != nil {
+		return MemoProjection{}, err
+	}
+	if !ok {
+		return MemoProjection{}, ErrNotFound
+	}
+	return p, nil
+}
+
+// loadEvents returns the memo's events with id > after, bounded by at.
+func loadEvents(db *gorm.DB, memoID, userID, after uint64, at AsOf) ([]MemoEvent, error) {
+	q := db.Where("memo_id=? AND user_id=? AND id > ?", memoID, userID, after)
+	if at.Version != 0 {
+		q = q.Where("id <= ?", at.Version)
+	}
+	if at.Time != nil {
+		q = q.Where("created_at <= ?", *at.Time)
+	}
+
+	var evs []MemoEvent
+	if err := q.Order("id asc").Find(&evs).Error; err != nil {
+		return nil, err
+	}
+	return evs, nil
+}
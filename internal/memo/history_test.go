@@ -0,0 +1,17 @@
+package memo
+
+import "testing"
+
+func TestParseAsOf(t *testing.T) {
+	if at, err := ParseAsOf(" 42 "); err != nil || at.Version != 42 || at.Time != nil {
+		t.Fatalf("42: %+v %v", at, err)
+	}
+	if at, err := ParseAsOf("2026-10-14T10:00:00+07:00"); err != nil || at.Time == nil || at.Version != 0 {
+		t.Fatalf("time: %+v %v", at, err)
+	}
+	for _, s := range []string{"0", "-1", "yesterday", ""} {
+		if at, err := ParseAsOf(s); err == nil {
+			t.Errorf("%q: got %+v, want an error", s, at)
+		}
+	}
+}
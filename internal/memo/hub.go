@@ -0,0 +1,139 @@
+package memo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// EventsChannel is the NOTIFY channel insertEvent fires on, with payload
+// "<user_id>:<event_id>". Postgres delivers it only when the tx commits.
+const EventsChannel = "memo_events"
+
+func notifyEvent(tx *gorm.DB, ev *MemoEvent) error {
+	return tx.Exec(`select pg_notify(?, ?)`, EventsChannel, fmt.Sprintf("%d:%d", ev.UserID, ev.ID)).Error
+}
+
+// EventHub LISTENs on EventsChannel and wakes the subscribers of the
+// event's user. Subscribers treat a wake-up as "query memo_events again",
+// so a dropped notification only delays delivery.
+type EventHub struct {
+	dsn string
+
+	mu   sync.Mutex
+	subs map[uint64]map[chan struct{}]struct{}
+}
+
+func NewEventHub(dsn string) *EventHub {
+	return &EventHub{dsn: dsn, subs: map[uint64]map[chan struct{}]struct{}{}}
+}
+
+// Subscribe returns a wake-up channel for userID and a cancel func.
+func (h *EventHub) Subscribe(userID uint64) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = map[chan struct{}]struct{}{}
+	}
+	h.subs[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs[userID], ch)
+		if len(h.subs[userID]) == 0 {
+			delete(h.subs, userID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *EventHub) wake(userID uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[userID] {
+		select {
+		case ch <- struct{}{}:
+		default: // already pending
+		}
+	}
+}
+
+func (h *EventHub) wakeAll() {
+	h.mu.Lock()
+	users := make([]uint64, 0, len(h.subs))
+	for uid := range h.subs {
+		users = append(users, uid)
+	}
+	h.mu.Unlock()
+
+	for _, uid := range users {
+		h.wake(uid)
+	}
+}
+
+// Run listens until ctx is done. A failed LISTEN is retried with backoff.
+func (h *EventHub) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := h.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("event hub listen error: %v (retry in %s)\n", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+// listen returns when ctx is done, or with the error of LISTEN.
+func (h *EventHub) listen(ctx context.Context) error {
+	l := pq.NewListener(h.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("event hub listener: %v\n", err)
+		}
+	})
+	defer l.Close()
+	// Listen blocks while the database is down; closing unblocks it
+	stop := context.AfterFunc(ctx, func() { l.Close() })
+	defer stop()
+
+	if err := l.Listen(EventsChannel); err != nil {
+		return err
+	}
+	// anything sent before this LISTEN was missed
+	h.wakeAll()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-l.Notify:
+			if n == nil {
+				// reconnected: notifications may have been lost
+				h.wakeAll()
+				continue
+			}
+			uidStr, _, _ := strings.Cut(n.Extra, ":")
+			uid, err := strconv.ParseUint(uidStr, 10, 64)
+			if err != nil {
+				continue
+			}
+			h.wake(uid)
+		case <-time.After(90 * time.Second):
+			go func() { _ = l.Ping() }()
+		}
+	}
+}
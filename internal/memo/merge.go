@@ -0,0 +1,120 @@
+package memo
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// hunk replaces base lines [start, end) with lines.
+type hunk struct {
+	start, end int
+	lines      []string
+	ours       bool
+}
+
+func hunksOf(ops []DiffOp, ours bool) []hunk {
+	var out []hunk
+	i := 0 // index into base
+	for k := 0; k < len(ops); {
+		if ops[k].Op == "equal" {
+			i++
+			k++
+			continue
+		}
+		h := hunk{start: i, ours: ours}
+		for k < len(ops) && ops[k].Op != "equal" {
+			if ops[k].Op == "delete" {
+				i++
+			} else {
+				h.lines = append(h.lines, ops[k].Text)
+			}
+			k++
+		}
+		h.end = i
+		out = append(out, h)
+	}
+	return out
+}
+
+// Merge3 is a line-based three-way merge of two edits of base. Changes to
+// different regions are combined; identical changes are taken once. ok is
+// false when both sides changed the same (or adjacent) lines differently.
+func Merge3(base, ours, theirs string) (merged string, ok bool) {
+	if ours == theirs {
+		return ours, true
+	}
+	if ours == base {
+		return theirs, true
+	}
+	if theirs == base {
+		return ours, true
+	}
+
+	b := strings.Split(base, "\n")
+	hs := append(
+		hunksOf(diffTokens(b, strings.Split(ours, "\n")), true),
+		hunksOf(diffTokens(b, strings.Split(theirs, "\n")), false)...,
+	)
+	sort.SliceStable(hs, func(i, j int) bool {
+		if hs[i].start != hs[j].start {
+			return hs[i].start < hs[j].start
+		}
+		return hs[i].end < hs[j].end
+	})
+
+	var out []string
+	pos := 0
+	for i := 0; i < len(hs); {
+		// cluster hunks that overlap or touch
+		cs, ce := hs[i].start, hs[i].end
+		j := i + 1
+		for j < len(hs) && hs[j].start <= ce {
+			ce = max(ce, hs[j].end)
+			j++
+		}
+		cluster := hs[i:j]
+
+		out = append(out, b[pos:cs]...)
+
+		var oursH, theirsH []hunk
+		for _, h := range cluster {
+			if h.ours {
+				oursH = append(oursH, h)
+			} else {
+				theirsH = append(theirsH, h)
+			}
+		}
+		switch {
+		case len(theirsH) == 0:
+			out = append(out, applyHunks(b, cs, ce, oursH)...)
+		case len(oursH) == 0:
+			out = append(out, applyHunks(b, cs, ce, theirsH)...)
+		default:
+			o := applyHunks(b, cs, ce, oursH)
+			t := applyHunks(b, cs, ce, theirsH)
+			if !slices.Equal(o, t) {
+				return "", false
+			}
+			out = append(out, o...)
+		}
+
+		pos = ce
+		i = j
+	}
+	out = append(out, b[pos:]...)
+
+	return strings.Join(out, "\n"), true
+}
+
+// applyHunks renders base[cs:ce] with one side's (non-overlapping) hunks applied.
+func applyHunks(base []string, cs, ce int, hs []hunk) []string {
+	var out []string
+	pos := cs
+	for _, h := range hs {
+		out = append(out, base[pos:h.start]...)
+		out = append(out, h.lines...)
+		pos = h.end
+	}
+	return append(out, base[pos:ce]...)
+}
@@ -0,0 +1,48 @@
+package memo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	lines := func(s ...string) string { return strings.Join(s, "\n") }
+	base := lines("a", "b", "c", "d", "e")
+
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflict           bool
+	}{
+		{"disjoint edits", base, lines("A", "b", "c", "d", "e"), lines("a", "b", "c", "d", "E"),
+			lines("A", "b", "c", "d", "E"), false},
+		{"disjoint insert and delete", base, lines("a", "b", "c", "d", "e", "f"), lines("a", "c", "d", "e"),
+			lines("a", "c", "d", "e", "f"), false},
+		{"adjacent lines conflict", base, lines("a", "B", "c", "d", "e"), lines("a", "b", "C", "d", "e"),
+			"", true},
+		{"same line differently", base, lines("a", "B1", "c", "d", "e"), lines("a", "B2", "c", "d", "e"),
+			"", true},
+		{"identical edits", base, lines("a", "B", "c", "d", "e"), lines("a", "B", "c", "d", "e"),
+			lines("a", "B", "c", "d", "e"), false},
+		{"identical edit plus a disjoint one", base, lines("a", "B", "c", "d", "E"), lines("a", "B", "c", "d", "e"),
+			lines("a", "B", "c", "d", "E"), false},
+		{"only ours changed", base, lines("a", "b", "X"), base, lines("a", "b", "X"), false},
+		{"only theirs changed", base, base, lines("a", "b", "X"), lines("a", "b", "X"), false},
+		{"empty base, one side", "", "new memo", "", "new memo", false},
+		{"empty base, same text", "", "new memo", "new memo", "new memo", false},
+		{"empty base, different text", "", "ours", "theirs", "", true},
+	}
+	for _, tt := range tests {
+		got, ok := Merge3(tt.base, tt.ours, tt.theirs)
+		if tt.conflict {
+			if ok {
+				t.Errorf("%s: merged to %q, want a conflict", tt.name, got)
+			}
+			continue
+		}
+		if !ok || got != tt.want {
+			t.Errorf("%s: got (%q, %v), want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
@@ -22,17 +22,26 @@ type MemoEvent struct {
 	UserID         uint64          `gorm:"index;not null"`
 	Type           string          `gorm:"not null"`
 	Payload        json.RawMessage `gorm:"type:jsonb;not null;default:'{}'::jsonb"`
+	SchemaVersion  int             `gorm:"not null;default:1"` // payload shape, see Upcast
 	IdempotencyKey *string         `gorm:"index"`
 	CreatedAt      time.Time       `gorm:"not null;default:now()"`
 }
 
 // MemoProjection is the current state for fast read/search.
 type MemoProjection struct {
-	MemoID   uint64     `gorm:"primaryKey"`
-	UserID   uint64     `gorm:"index;not null"`
-	Content  string     `gorm:"type:text;not null;default:''"`
-	Archived bool       `gorm:"not null;default:false"`
-	RemindAt *time.Time `gorm:"type:timestamptz"`
+	MemoID   uint64 `gorm:"primaryKey"`
+	UserID   uint64 `gorm:"index;not null"`
+	Content  string `gorm:"type:text;not null;default:''"`
+	Archived bool   `gorm:"not null;default:false"`
+
+	// RemindAt and ReminderStatus summarize Reminders: the earliest due
+	// reminder, or FIRED when only fired ones are left.
+	RemindAt       *time.Time `gorm:"type:timestamptz"`
+	ReminderStatus string     `gorm:"type:text;not null;default:''"`
+	LastFiredAt    *time.Time `gorm:"type:timestamptz"`
+
+	// Reminders is stored in memo_reminders (see LoadReminders), sorted by id.
+	Reminders []MemoReminder `gorm:"-"`
 
 	Tags pq.StringArray `gorm:"type:text[];not null;default:'{}'"`
 
@@ -40,6 +49,28 @@ type MemoProjection struct {
 	UpdatedAt time.Time `gorm:"index;not null;default:now()"`
 }
 
+// MemoReminder is one of a memo's reminders, projected from the REMINDER_*
+// events carrying its ReminderID.
+type MemoReminder struct {
+	MemoID     uint64 `gorm:"primaryKey" json:"memo_id"`
+	ReminderID string `gorm:"primaryKey;type:text" json:"reminder_id"`
+	UserID     uint64 `gorm:"index;not null" json:"user_id"`
+
+	RemindAt *time.Time `gorm:"type:timestamptz" json:"remind_at"`
+	Status   string     `gorm:"type:text;not null;default:''" json:"status"` // PENDING, SNOOZED or FIRED
+
+	// Rule is an RFC 5545 RRULE anchored at Start (DTSTART); RemindAt is
+	// then the next occurrence.
+	Rule  string     `gorm:"type:text;not null;default:''" json:"rule,omitempty"`
+	Start *time.Time `gorm:"type:timestamptz" json:"start,omitempty"`
+
+	FiredCount  int        `gorm:"not null;default:0" json:"fired_count"`
+	SnoozeCount int        `gorm:"not null;default:0" json:"snooze_count"`
+	LastFiredAt *time.Time `gorm:"type:timestamptz" json:"last_fired_at"`
+}
+
+func (MemoReminder) TableName() string { return "memo_reminders" }
+
 // Tag is a normalized hashtag per user.
 type Tag struct {
 	ID        uint64    `gorm:"primaryKey"`
@@ -54,3 +85,18 @@ type MemoTag struct {
 	UserID uint64 `gorm:"index;not null"`
 	TagID  uint64 `gorm:"primaryKey"`
 }
+
+// MemoSnapshot is a folded projection at Version (last event id folded in),
+// so rebuilds start there instead of at CREATED. Rev is ProjectionRev at
+// write time; At is the created_at of the event at Version.
+type MemoSnapshot struct {
+	ID      uint64          `gorm:"primaryKey"`
+	MemoID  uint64          `gorm:"index;not null"`
+	UserID  uint64          `gorm:"index;not null"`
+	Version uint64          `gorm:"not null"`
+	Rev     int             `gorm:"not null"`
+	At      time.Time       `gorm:"not null"`
+	State   json.RawMessage `gorm:"type:jsonb;not null"`
+
+	CreatedAt time.Time `gorm:"not null;default:now()"`
+}
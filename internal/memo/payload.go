@@ -0,0 +1,191 @@
+package memo
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Typed payloads, one per event type. Changing a shape means bumping the
+// type's entry in schemaVersions and registering an upcaster from the old
+// version, so events already in the log keep decoding.
+
+type CreatedPayload struct {
+	Content string `json:"content"`
+}
+
+type UpdatedPayload struct {
+	Content string `json:"content"`
+}
+
+// ReminderSetPayload: with Rule, RemindAt is DTSTART (the first occurrence).
+type ReminderSetPayload struct {
+	ReminderID string    `json:"reminder_id"` // v2
+	RemindAt   time.Time `json:"remind_at"`
+	Rule       string    `json:"rule,omitempty"`
+}
+
+type ReminderClearedPayload struct {
+	ReminderID string `json:"reminder_id"` // v2; v1 was empty
+}
+
+// RevertedPayload carries the full restored state, so folding it never
+// has to look back in the log.
+type RevertedPayload struct {
+	ToEventID uint64         `json:"to_event_id"`
+	Content   string         `json:"content"`
+	Archived  bool           `json:"archived"`
+	Reminders []MemoReminder `json:"reminders"` // v3; v2 had one remind_at + reminder_status
+}
+
+// ReminderFiredPayload is written by the worker, never by clients.
+type ReminderFiredPayload struct {
+	ReminderID string    `json:"reminder_id"` // v2
+	FiredAt    time.Time `json:"fired_at"`
+	Channels   []string  `json:"channels"`
+	JobID      uint64    `json:"job_id"`
+
+	// NextAt is the next occurrence of a recurring reminder; nil when the
+	// reminder is done.
+	NextAt *time.Time `json:"next_at,omitempty"`
+}
+
+// ReminderSnoozedPayload stores the resolved time, so replays do not
+// depend on when they run. Snooze is the spec the user picked.
+type ReminderSnoozedPayload struct {
+	ReminderID string    `json:"reminder_id"` // v2
+	Snooze     string    `json:"snooze"`
+	Until      time.Time `json:"until"`
+}
+
+// EmptyPayload is used by ARCHIVED and RESTORED.
+type EmptyPayload struct{}
+
+// schemaVersions is the current payload version per event type.
+var schemaVersions = map[string]int{
+	"CREATED":          1,
+	"UPDATED":          1,
+	"ARCHIVED":         1,
+	"RESTORED":         1,
+	"REMINDER_SET":     2,
+	"REMINDER_CLEARED": 2,
+	"REVERTED":         3,
+	"REMINDER_FIRED":   2,
+	"REMINDER_SNOOZED": 2,
+}
+
+// KnownEventType reports whether typ is an event type of the memo log.
+func KnownEventType(typ string) bool {
+	_, ok := schemaVersions[typ]
+	return ok
+}
+
+// Upcaster rewrites a payload from one schema version to the next.
+type Upcaster func(payload json.RawMessage) (json.RawMessage, error)
+
+type upcastKey struct {
+	typ  string
+	from int
+}
+
+var upcasters = map[upcastKey]Upcaster{}
+
+// RegisterUpcaster registers fn to upgrade typ payloads from version from
+// to from+1. Call it from init.
+func RegisterUpcaster(typ string, from int, fn Upcaster) {
+	upcasters[upcastKey{typ: typ, from: from}] = fn
+}
+
+// CurrentSchemaVersion is the version new events of typ are written with.
+func CurrentSchemaVersion(typ string) int {
+	if v, ok := schemaVersions[typ]; ok {
+		return v
+	}
+	return 1
+}
+
+// Upcast brings ev.Payload up to the current schema version in place.
+// Readers of the log (timeline, replay, Apply) call it before decoding.
+func Upcast(ev *MemoEvent) error {
+	if ev.SchemaVersion == 0 {
+		ev.SchemaVersion = 1
+	}
+	cur := CurrentSchemaVersion(ev.Type)
+	for ev.SchemaVersion < cur {
+		fn, ok := upcasters[upcastKey{typ: ev.Type, from: ev.SchemaVersion}]
+		if !ok {
+			return fmt.Errorf("event %d: no upcaster for %s v%d", ev.ID, ev.Type, ev.SchemaVersion)
+		}
+		b, err := fn(ev.Payload)
+		if err != nil {
+			return fmt.Errorf("event %d: upcast %s v%d: %w", ev.ID, ev.Type, ev.SchemaVersion, err)
+		}
+		ev.Payload = b
+		ev.SchemaVersion++
+	}
+	return nil
+}
+
+func init() {
+	// REVERTED v1 predates reminder_status: a restored remind_at was pending.
+	RegisterUpcaster("REVERTED", 1, func(b json.RawMessage) (json.RawMessage, error) {
+		var m map[string]any
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, err
+		}
+		m["reminder_status"] = ""
+		if m["remind_at"] != nil {
+			m["reminder_status"] = "PENDING"
+		}
+		return json.Marshal(m)
+	})
+
+	// v1 reminder events predate multiple reminders per memo.
+	for _, typ := range []string{"REMINDER_SET", "REMINDER_CLEARED", "REMINDER_FIRED", "REMINDER_SNOOZED"} {
+		RegisterUpcaster(typ, 1, func(b json.RawMessage) (json.RawMessage, error) {
+			m := map[string]any{}
+			if len(b) > 0 {
+				if err := json.Unmarshal(b, &m); err != nil {
+					return nil, err
+				}
+			}
+			m["reminder_id"] = DefaultReminderID
+			return json.Marshal(m)
+		})
+	}
+
+	// REVERTED v2 restored the single reminder as flat fields.
+	RegisterUpcaster("REVERTED", 2, func(b json.RawMessage) (json.RawMessage, error) {
+		var m map[string]any
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, err
+		}
+		reminders := []map[string]any{}
+		if st, _ := m["reminder_status"].(string); st != "" {
+			reminders = append(reminders, map[string]any{
+				"reminder_id": DefaultReminderID,
+				"remind_at":   m["remind_at"],
+				"status":      st,
+				"rule":        m["remind_rule"],
+				"start":       m["remind_start"],
+			})
+		}
+		for _, k := range []string{"remind_at", "reminder_status", "remind_rule", "remind_start"} {
+			delete(m, k)
+		}
+		m["reminders"] = reminders
+		return json.Marshal(m)
+	})
+}
+
+// DecodePayload upcasts ev and decodes its payload into T.
+func DecodePayload[T any](ev MemoEvent) (T, error) {
+	var out T
+	if err := Upcast(&ev); err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(ev.Payload, &out); err != nil {
+		return out, fmt.Errorf("event %d: %w", ev.ID, err)
+	}
+	return out, nil
+}
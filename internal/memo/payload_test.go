@@ -0,0 +1,121 @@
+package memo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Payloads as older versions wrote them, and what they decode to today.
+func TestUpcastFixtures(t *testing.T) {
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	later := at.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		typ     string
+		version int
+		payload string
+		decode  func(MemoEvent) (any, error)
+		want    any
+	}{
+		{
+			"REVERTED v1 with a reminder", "REVERTED", 1,
+			`{"to_event_id":4,"content":"rapat","archived":false,"remind_at":"2026-03-01T09:00:00Z"}`,
+			func(ev MemoEvent) (any, error) { return DecodePayload[RevertedPayload](ev) },
+			RevertedPayload{ToEventID: 4, Content: "rapat", Reminders: []MemoReminder{
+				{ReminderID: DefaultReminderID, RemindAt: &at, Status: "PENDING"},
+			}},
+		},
+		{
+			"REVERTED v1 without a reminder", "REVERTED", 1,
+			`{"to_event_id":4,"content":"rapat","archived":true,"remind_at":null}`,
+			func(ev MemoEvent) (any, error) { return DecodePayload[RevertedPayload](ev) },
+			RevertedPayload{ToEventID: 4, Content: "rapat", Archived: true, Reminders: []MemoReminder{}},
+		},
+		{
+			"REVERTED v2 recurring", "REVERTED", 2,
+			`{"to_event_id":7,"content":"minum obat","archived":false,"remind_at":"2026-03-01T10:00:00Z","reminder_status":"SNOOZED","remind_rule":"FREQ=DAILY","remind_start":"2026-03-01T09:00:00Z"}`,
+			func(ev MemoEvent) (any, error) { return DecodePayload[RevertedPayload](ev) },
+			RevertedPayload{ToEventID: 7, Content: "minum obat", Reminders: []MemoReminder{
+				{ReminderID: DefaultReminderID, RemindAt: &later, Status: "SNOOZED", Rule: "FREQ=DAILY", Start: &at},
+			}},
+		},
+		{
+			"REVERTED v2 fired", "REVERTED", 2,
+			`{"to_event_id":7,"content":"x","archived":false,"remind_at":null,"reminder_status":"FIRED"}`,
+			func(ev MemoEvent) (any, error) { return DecodePayload[RevertedPayload](ev) },
+			RevertedPayload{ToEventID: 7, Content: "x", Reminders: []MemoReminder{
+				{ReminderID: DefaultReminderID, Status: "FIRED"},
+			}},
+		},
+		{
+			"REMINDER_SET v1", "REMINDER_SET", 1,
+			`{"remind_at":"2026-03-01T09:00:00Z"}`,
+			func(ev MemoEvent) (any, error) { return DecodePayload[ReminderSetPayload](ev) },
+			ReminderSetPayload{ReminderID: DefaultReminderID, RemindAt: at},
+		},
+		{
+			"REMINDER_CLEARED v1", "REMINDER_CLEARED", 1, `{}`,
+			func(ev MemoEvent) (any, error) { return DecodePayload[ReminderClearedPayload](ev) },
+			ReminderClearedPayload{ReminderID: DefaultReminderID},
+		},
+		{
+			"REMINDER_CLEARED v1 without a payload", "REMINDER_CLEARED", 0, ``,
+			func(ev MemoEvent) (any, error) { return DecodePayload[ReminderClearedPayload](ev) },
+			ReminderClearedPayload{ReminderID: DefaultReminderID},
+		},
+		{
+			"REMINDER_FIRED v1", "REMINDER_FIRED", 1,
+			`{"fired_at":"2026-03-01T09:00:00Z","channels":["log","inapp"],"job_id":12}`,
+			func(ev MemoEvent) (any, error) { return DecodePayload[ReminderFiredPayload](ev) },
+			ReminderFiredPayload{ReminderID: DefaultReminderID, FiredAt: at, Channels: []string{"log", "inapp"}, JobID: 12},
+		},
+		{
+			"REMINDER_SNOOZED v1", "REMINDER_SNOOZED", 1,
+			`{"snooze":"1h","until":"2026-03-01T10:00:00Z"}`,
+			func(ev MemoEvent) (any, error) { return DecodePayload[ReminderSnoozedPayload](ev) },
+			ReminderSnoozedPayload{ReminderID: DefaultReminderID, Snooze: "1h", Until: later},
+		},
+		{
+			"current version untouched", "REMINDER_SET", 2,
+			`{"reminder_id":"pagi","remind_at":"2026-03-01T09:00:00Z","rule":"FREQ=DAILY"}`,
+			func(ev MemoEvent) (any, error) { return DecodePayload[ReminderSetPayload](ev) },
+			ReminderSetPayload{ReminderID: "pagi", RemindAt: at, Rule: "FREQ=DAILY"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := MemoEvent{ID: 1, Type: tt.typ, SchemaVersion: tt.version, Payload: json.RawMessage(tt.payload)}
+
+			up := ev
+			if err := Upcast(&up); err != nil {
+				t.Fatal(err)
+			}
+			if want := CurrentSchemaVersion(tt.typ); up.SchemaVersion != want {
+				t.Fatalf("schema version %d, want %d", up.SchemaVersion, want)
+			}
+
+			got, err := tt.decode(ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpcastMissingStep(t *testing.T) {
+	ev := MemoEvent{ID: 1, Type: "REVERTED", SchemaVersion: 1, Payload: json.RawMessage(`{}`)}
+	saved := upcasters[upcastKey{"REVERTED", 2}]
+	delete(upcasters, upcastKey{"REVERTED", 2})
+	defer func() { upcasters[upcastKey{"REVERTED", 2}] = saved }()
+
+	if err := Upcast(&ev); err == nil {
+		t.Fatal("upcast without a v2 step succeeded")
+	}
+}
@@ -0,0 +1,122 @@
+package memo
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Apply folds one event into p. Live writes and replays both go through here,
+// so the projection rules live in exactly one place.
+func Apply(p *MemoProjection, ev MemoEvent) error {
+	switch ev.Type {
+	case "CREATED":
+		pl, err := DecodePayload[CreatedPayload](ev)
+		if err != nil {
+			return err
+		}
+		p.Content = pl.Content
+		p.Tags = tagsOf(p.Content)
+	case "UPDATED":
+		pl, err := DecodePayload[UpdatedPayload](ev)
+		if err != nil {
+			return err
+		}
+		p.Content = pl.Content
+		p.Tags = tagsOf(p.Content)
+	case "ARCHIVED":
+		p.Archived = true
+	case "RESTORED":
+		p.Archived = false
+	case "REMINDER_SET":
+		pl, err := DecodePayload[ReminderSetPayload](ev)
+		if err != nil {
+			return err
+		}
+		r := MemoReminder{ReminderID: pl.ReminderID, RemindAt: &pl.RemindAt, Status: "PENDING", Rule: pl.Rule}
+		if pl.Rule != "" {
+			r.Start = &pl.RemindAt
+		}
+		if old := p.Reminder(pl.ReminderID); old != nil {
+			r.LastFiredAt = old.LastFiredAt
+		}
+		p.putReminder(r)
+	case "REMINDER_CLEARED":
+		pl, err := DecodePayload[ReminderClearedPayload](ev)
+		if err != nil {
+			return err
+		}
+		p.removeReminder(pl.ReminderID)
+	case "REMINDER_FIRED":
+		pl, err := DecodePayload[ReminderFiredPayload](ev)
+		if err != nil {
+			return err
+		}
+		p.LastFiredAt = &pl.FiredAt
+		// the reminder may have been cleared while it was being sent
+		if r := p.Reminder(pl.ReminderID); r != nil {
+			r.LastFiredAt = &pl.FiredAt
+			r.FiredCount++
+			if pl.NextAt != nil {
+				r.RemindAt = pl.NextAt
+				r.Status = "PENDING"
+			} else {
+				r.RemindAt = nil
+				r.Status = "FIRED"
+			}
+		}
+	case "REMINDER_SNOOZED":
+		pl, err := DecodePayload[ReminderSnoozedPayload](ev)
+		if err != nil {
+			return err
+		}
+		if r := p.Reminder(pl.ReminderID); r != nil {
+			r.RemindAt = &pl.Until
+			r.Status = "SNOOZED"
+			r.SnoozeCount++
+		}
+	case "REVERTED":
+		pl, err := DecodePayload[RevertedPayload](ev)
+		if err != nil {
+			return err
+		}
+		p.Content = pl.Content
+		p.Tags = tagsOf(p.Content)
+		p.Archived = pl.Archived
+		p.revertReminders(pl.Reminders)
+	default:
+		return fmt.Errorf("event %d: %w: %s", ev.ID, ErrInvalidEvent, ev.Type)
+	}
+
+	for i := range p.Reminders {
+		p.Reminders[i].MemoID = ev.MemoID
+		p.Reminders[i].UserID = ev.UserID
+	}
+	p.summarizeReminders()
+
+	p.MemoID = ev.MemoID
+	p.UserID = ev.UserID
+	p.Version = ev.ID
+	p.UpdatedAt = ev.CreatedAt
+	return nil
+}
+
+// Fold rebuilds a projection from a memo's events, ordered by id.
+func Fold(events []MemoEvent) (MemoProjection, error) {
+	var p MemoProjection
+	for _, ev := range events {
+		if err := Apply(&p, ev); err != nil {
+			return MemoProjection{}, err
+		}
+	}
+	return p, nil
+}
+
+// tagsOf never returns nil: the tags column is not null.
+func tagsOf(content string) pq.StringArray {
+	tags := ExtractTags(content)
+	if tags == nil {
+		return pq.StringArray{}
+	}
+	return pq.StringArray(tags)
+}
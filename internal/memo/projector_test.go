@@ -0,0 +1,54 @@
+package memo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFoldRevertKeepsFireHistory(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	t1, t2 := t0.Add(time.Hour), t0.Add(2*time.Hour)
+	var id uint64
+	ev := func(typ string, payload any) MemoEvent {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id++
+		return MemoEvent{ID: id, MemoID: 1, UserID: 2, Type: typ, Payload: b, SchemaVersion: CurrentSchemaVersion(typ), CreatedAt: t0}
+	}
+	target := []MemoReminder{
+		{ReminderID: "a", RemindAt: &t1, Status: "PENDING"},
+		{ReminderID: "b", RemindAt: &t1, Status: "PENDING"},
+	}
+	p, err := Fold([]MemoEvent{
+		ev("CREATED", CreatedPayload{Content: "rapat"}),
+		ev("REMINDER_SET", ReminderSetPayload{ReminderID: "a", RemindAt: t1}),
+		ev("REMINDER_SET", ReminderSetPayload{ReminderID: "b", RemindAt: t1}),
+		ev("REMINDER_FIRED", ReminderFiredPayload{ReminderID: "a", FiredAt: t1, Channels: []string{}}),
+		ev("REMINDER_SNOOZED", ReminderSnoozedPayload{ReminderID: "b", Snooze: "1h", Until: t2}),
+		ev("UPDATED", UpdatedPayload{Content: "rapat besar"}),
+		ev("REVERTED", RevertedPayload{ToEventID: 3, Content: "rapat", Reminders: target}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Content != "rapat" {
+		t.Fatalf("content %q", p.Content)
+	}
+	if p.LastFiredAt == nil || !p.LastFiredAt.Equal(t1) {
+		t.Fatalf("last_fired_at %v", p.LastFiredAt)
+	}
+
+	// a already fired at its target time: it stays fired
+	a := p.Reminder("a")
+	if a == nil || a.Status != "FIRED" || a.RemindAt != nil || a.FiredCount != 1 || a.LastFiredAt == nil {
+		t.Fatalf("a %+v", a)
+	}
+	// b never fired: its schedule goes back, the snooze count does not
+	b := p.Reminder("b")
+	if b == nil || b.Status != "PENDING" || b.RemindAt == nil || !b.RemindAt.Equal(t1) || b.SnoozeCount != 1 {
+		t.Fatalf("b %+v", b)
+	}
+}
@@ -0,0 +1,208 @@
+package memo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"tell/internal/auth"
+	"tell/internal/jobs"
+	"tell/internal/rrule"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultReminderID is the reminder used when a request names none, and
+// the one events and jobs from before multiple reminders are read as.
+const DefaultReminderID = "default"
+
+// MaxReminders caps the reminders of one memo.
+const MaxReminders = 20
+
+var reminderIDRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ValidReminderID reports whether id may name a reminder.
+func ValidReminderID(id string) bool { return reminderIDRe.MatchString(id) }
+
+// Reminder returns the reminder with id, or nil.
+func (p *MemoProjection) Reminder(id string) *MemoReminder {
+	for i := range p.Reminders {
+		if p.Reminders[i].ReminderID == id {
+			return &p.Reminders[i]
+		}
+	}
+	return nil
+}
+
+func (p *MemoProjection) putReminder(r MemoReminder) {
+	if old := p.Reminder(r.ReminderID); old != nil {
+		*old = r
+		return
+	}
+	p.Reminders = append(p.Reminders, r)
+	sort.Slice(p.Reminders, func(i, j int) bool { return p.Reminders[i].ReminderID < p.Reminders[j].ReminderID })
+}
+
+func (p *MemoProjection) removeReminder(id string) {
+	for i := range p.Reminders {
+		if p.Reminders[i].ReminderID == id {
+			p.Reminders = append(p.Reminders[:i], p.Reminders[i+1:]...)
+			return
+		}
+	}
+}
+
+// revertReminders restores the schedule of target, the reminders of an
+// earlier version. Fire and snooze counters and LastFiredAt are history and
+// stay as they are now, and a reminder whose target occurrence has already
+// fired keeps its current schedule so the revert cannot send it again.
+func (p *MemoProjection) revertReminders(target []MemoReminder) {
+	out := make([]MemoReminder, 0, len(target))
+	for _, r := range target {
+		if cur := p.Reminder(r.ReminderID); cur != nil {
+			if r.RemindAt != nil && cur.LastFiredAt != nil && !r.RemindAt.After(*cur.LastFiredAt) {
+				r.RemindAt, r.Status, r.Rule, r.Start = cur.RemindAt, cur.Status, cur.Rule, cur.Start
+			}
+			r.FiredCount, r.SnoozeCount, r.LastFiredAt = cur.FiredCount, cur.SnoozeCount, cur.LastFiredAt
+		}
+		out = append(out, r)
+	}
+	p.Reminders = out
+}
+
+// summarizeReminders fills RemindAt and ReminderStatus from Reminders.
+func (p *MemoProjection) summarizeReminders() {
+	p.RemindAt, p.ReminderStatus = nil, ""
+	for _, r := range p.Reminders {
+		if r.RemindAt != nil && (p.RemindAt == nil || r.RemindAt.Before(*p.RemindAt)) {
+			p.RemindAt, p.ReminderStatus = r.RemindAt, r.Status
+		}
+	}
+	if p.RemindAt == nil && len(p.Reminders) > 0 {
+		p.ReminderStatus = "FIRED"
+	}
+}
+
+// LoadReminders reads p's stored reminders into p.Reminders.
+func LoadReminders(db *gorm.DB, p *MemoProjection) error {
+	p.Reminders = nil
+	return db.Where("memo_id = ?", p.MemoID).Order("reminder_id asc").Find(&p.Reminders).Error
+}
+
+// saveReminders replaces the stored reminders of p's memo.
+func saveReminders(tx *gorm.DB, p MemoProjection) error {
+	if err := tx.Where("memo_id = ?", p.MemoID).Delete(&MemoReminder{}).Error; err != nil {
+		return err
+	}
+	if len(p.Reminders) == 0 {
+		return nil
+	}
+	return tx.Create(&p.Reminders).Error
+}
+
+// RecordReminderFired appends the system REMINDER_FIRED event for a
+// dispatched reminder job. For a recurring reminder it also enqueues the
+// next occurrence in the same tx. It is idempotent per job, so a worker
+// retry after a crash does not log the firing (or enqueue) twice.
+func (s *Service) RecordReminderFired(ctx context.Context, f jobs.ReminderFired) error {
+	idem := fmt.Sprintf("reminder-fired:%d", f.JobID)
+	reminderID := f.ReminderID
+	if reminderID == "" {
+		reminderID = DefaultReminderID
+	}
+
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUserEvents(tx, f.UserID); err != nil {
+			return err
+		}
+		var p MemoProjection
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("memo_id=? AND user_id=?", f.MemoID, f.UserID).
+			First(&p).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := LoadReminders(tx, &p); err != nil {
+			return err
+		}
+
+		var n int64
+		if err := tx.Model(&MemoEvent{}).
+			Where("user_id=? AND idempotency_key=?", f.UserID, idem).
+			Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return nil
+		}
+
+		channels := f.Channels
+		if channels == nil {
+			channels = []string{}
+		}
+		var next *time.Time
+		if r := p.Reminder(reminderID); r != nil {
+			settings, err := auth.LoadSettings(tx, f.UserID)
+			if err != nil {
+				return err
+			}
+			next = nextOccurrence(*r, f.FiredAt, settings.Location())
+		}
+		ev, err := s.insertEvent(tx, f.MemoID, f.UserID, "REMINDER_FIRED", ReminderFiredPayload{
+			ReminderID: reminderID,
+			FiredAt:    f.FiredAt,
+			Channels:   channels,
+			JobID:      f.JobID,
+			NextAt:     next,
+		}, &idem)
+		if err != nil {
+			return err
+		}
+
+		if err := Apply(&p, *ev); err != nil {
+			return err
+		}
+		p.UpdatedAt = time.Now()
+		if err := tx.Save(&p).Error; err != nil {
+			return err
+		}
+		if err := saveReminders(tx, p); err != nil {
+			return err
+		}
+		if next != nil {
+			if err := enqueueReminder(tx, f.UserID, f.MemoID, reminderID, *next); err != nil {
+				return err
+			}
+		}
+		return s.maybeSnapshot(tx, p)
+	})
+}
+
+// nextOccurrence is the next run of a recurring reminder after the one
+// that just fired (occurrences missed while the worker was down are
+// skipped), or nil when there is no rule or it is exhausted. The series
+// keeps its wall-clock time in loc across DST changes.
+func nextOccurrence(r MemoReminder, firedAt time.Time, loc *time.Location) *time.Time {
+	if r.Rule == "" || r.Start == nil {
+		return nil
+	}
+	rule, err := rrule.Parse(r.Rule)
+	if err != nil {
+		return nil
+	}
+	after := firedAt
+	if r.RemindAt != nil && r.RemindAt.After(after) {
+		after = *r.RemindAt
+	}
+	t, ok := rule.Next(r.Start.In(loc), after)
+	if !ok {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,399 @@
+package memo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// shadowTables are the shadow tables of one replay run; the suffix keeps
+// concurrent runs apart.
+type shadowTables struct {
+	projections, reminders string
+}
+
+func newShadowTables() (shadowTables, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return shadowTables{}, err
+	}
+	suffix := hex.EncodeToString(b)
+	return shadowTables{
+		projections: "memo_projections_shadow_" + suffix,
+		reminders:   "memo_reminders_shadow_" + suffix,
+	}, nil
+}
+
+func (t shadowTables) drop(db *gorm.DB) {
+	_ = db.Exec(`drop table if exists ` + t.projections + `, ` + t.reminders).Error
+}
+
+// insert writes rebuilt projections and their reminders to the shadow tables.
+func (t shadowTables) insert(db *gorm.DB, rebuilt []MemoProjection) error {
+	if len(rebuilt) == 0 {
+		return nil
+	}
+	if err := db.Table(t.projections).Create(&rebuilt).Error; err != nil {
+		return err
+	}
+	var rems []MemoReminder
+	for _, p := range rebuilt {
+		rems = append(rems, p.Reminders...)
+	}
+	if len(rems) == 0 {
+		return nil
+	}
+	return db.Table(t.reminders).Create(&rems).Error
+}
+
+type ReplayOptions struct {
+	UserID uint64 // 0 = all users
+	DryRun bool   // only report diffs, write nothing
+	Shadow bool   // build into shadow table, then swap in one tx
+
+	// FromSnapshots starts each memo from its latest snapshot of the
+	// current ProjectionRev instead of CREATED. Faster, but a snapshot
+	// written by a changed Apply without a rev bump is taken as is.
+	FromSnapshots bool
+}
+
+// ProjectionDiff lists the columns where a rebuilt projection differs
+// from the stored row. Fields is ["missing"] when there is no stored row
+// and ["orphan"] when a stored row has no events behind it.
+type ProjectionDiff struct {
+	MemoID uint64
+	UserID uint64
+	Fields []string
+}
+
+type ReplayReport struct {
+	Memos  int
+	Events int
+	Diffs  []ProjectionDiff
+}
+
+// Replayer rebuilds memo_projections and memo_reminders from memo_events.
+type Replayer struct {
+	DB        *gorm.DB
+	BatchSize int
+}
+
+func (r *Replayer) Replay(ctx context.Context, opts ReplayOptions) (ReplayReport, error) {
+	var rep ReplayReport
+	db := r.DB.WithContext(ctx)
+
+	batch := r.BatchSize
+	if batch <= 0 {
+		batch = 200
+	}
+
+	var shadow shadowTables
+	if opts.Shadow && !opts.DryRun {
+		var err error
+		if shadow, err = newShadowTables(); err != nil {
+			return rep, err
+		}
+		if err := db.Exec(`create table ` + shadow.projections + ` (like memo_projections including defaults)`).Error; err != nil {
+			return rep, err
+		}
+		defer shadow.drop(db) // already gone after a swap
+		if err := db.Exec(`create table ` + shadow.reminders + ` (like memo_reminders including defaults)`).Error; err != nil {
+			return rep, err
+		}
+	}
+
+	memos := db.Model(&Memo{}).Order("id asc")
+	if opts.UserID != 0 {
+		memos = memos.Where("user_id = ?", opts.UserID)
+	}
+
+	var rows []Memo
+	res := memos.FindInBatches(&rows, batch, func(_ *gorm.DB, _ int) error {
+		rebuilt, err := r.fold(db, rows, opts, &rep)
+		if err != nil {
+			return err
+		}
+		if opts.DryRun || len(rebuilt) == 0 {
+			return nil
+		}
+		if opts.Shadow {
+			return shadow.insert(db, rebuilt)
+		}
+		for i := range rebuilt {
+			if err := r.saveInPlace(db, rebuilt[i], opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if res.Error != nil {
+		return rep, res.Error
+	}
+
+	if opts.DryRun {
+		return rep, nil
+	}
+	if opts.Shadow {
+		return rep, r.swapShadow(db, shadow, opts)
+	}
+
+	// in place: drop projections without a memo behind them
+	for _, model := range []any{&MemoProjection{}, &MemoReminder{}} {
+		q := db.Where("memo_id not in (select id from memos)")
+		if opts.UserID != 0 {
+			q = q.Where("user_id = ?", opts.UserID)
+		}
+		if err := q.Delete(model).Error; err != nil {
+			return rep, err
+		}
+	}
+	return rep, nil
+}
+
+// fold rebuilds the projections of rows from their latest snapshot and
+// events, adding counts and diffs against the stored rows to rep.
+func (r *Replayer) fold(db *gorm.DB, rows []Memo, opts ReplayOptions, rep *ReplayReport) ([]MemoProjection, error) {
+	ids := make([]uint64, 0, len(rows))
+	for _, m := range rows {
+		ids = append(ids, m.ID)
+	}
+
+	// with FromSnapshots, start each memo from its latest snapshot of the
+	// current rev; rev -1 matches none
+	rev := -1
+	if opts.FromSnapshots {
+		rev = ProjectionRev
+	}
+	var snaps []MemoSnapshot
+	if err := db.Raw(`
+		select distinct on (memo_id) *
+		from memo_snapshots
+		where memo_id in ? and rev = ?
+		order by memo_id, version desc
+	`, ids, rev).Scan(&snaps).Error; err != nil {
+		return nil, err
+	}
+	bySnap := map[uint64]MemoSnapshot{}
+	for _, sn := range snaps {
+		bySnap[sn.MemoID] = sn
+	}
+
+	var evs []MemoEvent
+	if err := db.Raw(`
+		select e.*
+		from memo_events e
+		left join (
+			select memo_id, max(version) as version
+			from memo_snapshots
+			where memo_id in ? and rev = ?
+			group by memo_id
+		) s on s.memo_id = e.memo_id
+		where e.memo_id in ? and e.id > coalesce(s.version, 0)
+		order by e.memo_id asc, e.id asc
+	`, ids, rev, ids).Scan(&evs).Error; err != nil {
+		return nil, err
+	}
+	rep.Events += len(evs)
+
+	byMemo := map[uint64][]MemoEvent{}
+	for _, e := range evs {
+		byMemo[e.MemoID] = append(byMemo[e.MemoID], e)
+	}
+
+	var current []MemoProjection
+	if err := db.Where("memo_id in ?", ids).Find(&current).Error; err != nil {
+		return nil, err
+	}
+	var currentReminders []MemoReminder
+	if err := db.Where("memo_id in ?", ids).Order("memo_id asc, reminder_id asc").Find(&currentReminders).Error; err != nil {
+		return nil, err
+	}
+	stored := map[uint64]MemoProjection{}
+	for _, p := range current {
+		stored[p.MemoID] = p
+	}
+	for _, r := range currentReminders {
+		if p, ok := stored[r.MemoID]; ok {
+			p.Reminders = append(p.Reminders, r)
+			stored[r.MemoID] = p
+		}
+	}
+
+	rebuilt := make([]MemoProjection, 0, len(rows))
+	for _, m := range rows {
+		mevs := byMemo[m.ID]
+		snap, hasSnap := bySnap[m.ID]
+		if len(mevs) == 0 && !hasSnap {
+			if old, ok := stored[m.ID]; ok {
+				rep.Diffs = append(rep.Diffs, ProjectionDiff{MemoID: m.ID, UserID: old.UserID, Fields: []string{"orphan"}})
+			}
+			continue
+		}
+
+		var p MemoProjection
+		if hasSnap {
+			if err := json.Unmarshal(snap.State, &p); err != nil {
+				return nil, fmt.Errorf("memo %d snapshot: %w", m.ID, err)
+			}
+		}
+		for _, ev := range mevs {
+			if err := Apply(&p, ev); err != nil {
+				return nil, fmt.Errorf("memo %d: %w", m.ID, err)
+			}
+		}
+		rep.Memos++
+
+		old, ok := stored[m.ID]
+		if !ok {
+			rep.Diffs = append(rep.Diffs, ProjectionDiff{MemoID: m.ID, UserID: m.UserID, Fields: []string{"missing"}})
+		} else if fields := diffProjection(old, p); len(fields) > 0 {
+			rep.Diffs = append(rep.Diffs, ProjectionDiff{MemoID: m.ID, UserID: m.UserID, Fields: fields})
+		}
+		rebuilt = append(rebuilt, p)
+	}
+
+	return rebuilt, nil
+}
+
+// saveInPlace writes p over the live row. If events were appended since p
+// was folded, the memo is folded again under the row lock so they are not
+// lost. One memo per transaction: a single row lock cannot deadlock with
+// writers.
+func (r *Replayer) saveInPlace(db *gorm.DB, p MemoProjection, opts ReplayOptions) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var live MemoProjection
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("memo_id", "version").
+			Where("memo_id = ?", p.MemoID).Take(&live).Error
+		switch {
+		case err == nil && live.Version > p.Version:
+			again, err := r.fold(tx, []Memo{{ID: p.MemoID, UserID: p.UserID}}, opts, &ReplayReport{})
+			if err != nil {
+				return err
+			}
+			if len(again) == 0 {
+				return nil
+			}
+			p = again[0]
+		case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		if err := tx.Save(&p).Error; err != nil {
+			return err
+		}
+		return saveReminders(tx, p)
+	})
+}
+
+// swapShadow replaces the live rows in scope with the shadow tables in a
+// single transaction, so readers see either the old or the new projection.
+// Memos written since their fold (a newer live version, or no shadow row)
+// are folded again first, under the lock that keeps writers out.
+func (r *Replayer) swapShadow(db *gorm.DB, shadow shadowTables, opts ReplayOptions) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`lock table memo_projections, memo_reminders in exclusive mode`).Error; err != nil {
+			return err
+		}
+
+		var stale []Memo
+		if err := tx.Raw(`
+			select m.id, m.user_id
+			from memo_projections l
+			join memos m on m.id = l.memo_id
+			left join `+shadow.projections+` s on s.memo_id = l.memo_id
+			where (? = 0 or l.user_id = ?) and (s.memo_id is null or l.version > s.version)
+			order by m.id
+		`, opts.UserID, opts.UserID).Scan(&stale).Error; err != nil {
+			return err
+		}
+		if len(stale) > 0 {
+			rebuilt, err := r.fold(tx, stale, opts, &ReplayReport{})
+			if err != nil {
+				return err
+			}
+			ids := make([]uint64, len(stale))
+			for i, m := range stale {
+				ids[i] = m.ID
+			}
+			for _, t := range []string{shadow.projections, shadow.reminders} {
+				if err := tx.Exec(`delete from `+t+` where memo_id in ?`, ids).Error; err != nil {
+					return err
+				}
+			}
+			if err := shadow.insert(tx, rebuilt); err != nil {
+				return err
+			}
+		}
+
+		for _, t := range [][2]string{{"memo_projections", shadow.projections}, {"memo_reminders", shadow.reminders}} {
+			if err := tx.Exec(`delete from `+t[0]+` where (? = 0 or user_id = ?)`, opts.UserID, opts.UserID).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(`insert into ` + t[0] + ` select * from ` + t[1]).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(`drop table ` + t[1]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func diffProjection(old, p MemoProjection) []string {
+	var out []string
+	if old.UserID != p.UserID {
+		out = append(out, "user_id")
+	}
+	if old.Content != p.Content {
+		out = append(out, "content")
+	}
+	if old.Archived != p.Archived {
+		out = append(out, "archived")
+	}
+	if !sameTime(old.RemindAt, p.RemindAt) {
+		out = append(out, "remind_at")
+	}
+	if old.ReminderStatus != p.ReminderStatus {
+		out = append(out, "reminder_status")
+	}
+	if !sameTime(old.LastFiredAt, p.LastFiredAt) {
+		out = append(out, "last_fired_at")
+	}
+	if !sameReminders(old.Reminders, p.Reminders) {
+		out = append(out, "reminders")
+	}
+	if !slices.Equal([]string(old.Tags), []string(p.Tags)) {
+		out = append(out, "tags")
+	}
+	if old.Version != p.Version {
+		out = append(out, "version")
+	}
+	return out
+}
+
+func sameReminders(a, b []MemoReminder) bool {
+	return slices.EqualFunc(a, b, sameReminder)
+}
+
+func sameReminder(x, y MemoReminder) bool {
+	return x.ReminderID == y.ReminderID && x.UserID == y.UserID &&
+		sameTime(x.RemindAt, y.RemindAt) && x.Status == y.Status &&
+		x.Rule == y.Rule && sameTime(x.Start, y.Start) &&
+		x.FiredCount == y.FiredCount && x.SnoozeCount == y.SnoozeCount &&
+		sameTime(x.LastFiredAt, y.LastFiredAt)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
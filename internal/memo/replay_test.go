@@ -0,0 +1,83 @@
+package memo
+
+import (
+	"context"
+	"testing"
+)
+
+// An event appended between a memo's fold into the shadow table and the
+// swap must survive the swap.
+func TestReplayShadowKeepsLateEvents(t *testing.T) {
+	db := testDB(t)
+	s := &Service{DB: db}
+	ctx := context.Background()
+	uid := testUser()
+
+	memoID, err := s.CreateMemo(ctx, uid, CreateMemoInput{Content: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Replayer{DB: db}
+	opts := ReplayOptions{UserID: uid, Shadow: true}
+	shadow, err := newShadowTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shadow.drop(db)
+	for _, q := range []string{
+		`create table ` + shadow.projections + ` (like memo_projections including defaults)`,
+		`create table ` + shadow.reminders + ` (like memo_reminders including defaults)`,
+	} {
+		if err := db.Exec(q).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	rebuilt, err := r.fold(db, []Memo{{ID: memoID, UserID: uid}}, opts, &ReplayReport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shadow.insert(db, rebuilt); err != nil {
+		t.Fatal(err)
+	}
+
+	// lands after the fold, before the swap
+	v2 := "v2"
+	version, err := s.AppendEvent(ctx, AppendEventInput{MemoID: memoID, UserID: uid, Type: "UPDATED", Content: &v2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, err := s.CreateMemo(ctx, uid, CreateMemoInput{Content: "created during replay"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.swapShadow(db, shadow, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []MemoProjection
+	if err := db.Where("user_id = ?", uid).Order("memo_id").Find(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].MemoID != memoID || got[1].MemoID != later {
+		t.Fatalf("projections after swap: %+v", got)
+	}
+	if got[0].Content != "v2" || got[0].Version != version {
+		t.Fatalf("memo %d: content %q version %d, want v2 at %d", memoID, got[0].Content, got[0].Version, version)
+	}
+}
+
+func TestShadowTablesPerRun(t *testing.T) {
+	a, err := newShadowTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newShadowTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.projections == b.projections || a.reminders == b.reminders {
+		t.Fatalf("two runs share shadow tables: %+v %+v", a, b)
+	}
+}
@@ -4,25 +4,51 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"tell/internal/auth"
 	"tell/internal/jobs"
+	"tell/internal/rrule"
 	"time"
 
-	"github.com/lib/pq"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 var ErrNotFound = errors.New("not found")
 var ErrInvalidEvent = errors.New("invalid event")
+var ErrVersionConflict = errors.New("version conflict")
+
+// ConflictError is returned when AppendEventInput.ExpectedVersion is stale.
+// It matches ErrVersionConflict with errors.Is.
+type ConflictError struct {
+	Expected uint64
+	Current  uint64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("version conflict: expected %d, current %d", e.Expected, e.Current)
+}
+
+func (e *ConflictError) Is(target error) bool { return target == ErrVersionConflict }
 
 type Service struct {
 	DB *gorm.DB
+
+	// States validates transitions; nil uses NewStateMachine's defaults.
+	States *StateMachine
+
+	// SnapshotEvery writes a memo_snapshots row every N events
+	// (0 = DefaultSnapshotEvery).
+	SnapshotEvery int
 }
 
 type CreateMemoInput struct {
-	Content  string
-	RemindAt *time.Time
-	IdemKey  *string
+	Content    string
+	RemindAt   *time.Time
+	RemindRule string // optional RRULE, RemindAt is its start
+	IdemKey    *string
 }
 
 type AppendEventInput struct {
@@ -30,86 +56,106 @@ type AppendEventInput struct {
 	UserID   uint64
 	Type     string
 	Content  *string
-	RemindAt *time.Time
+	RemindAt *time.Time // REMINDER_SET; on UPDATED see DetectedPhrase
 	IdemKey  *string
+
+	// DetectedPhrase is the date/time phrase in Content that RemindAt was
+	// read from (ExtractReminder). An UPDATED with one also emits a
+	// REMINDER_SET, unless the previous content had the same phrase; an
+	// UPDATED without one ignores RemindAt.
+	DetectedPhrase string
+
+	// ReminderID names the reminder a REMINDER_* event targets
+	// ("" = DefaultReminderID).
+	ReminderID string
+
+	// RemindRule makes a REMINDER_SET recurring (RRULE, RemindAt = start).
+	RemindRule string
+
+	// REMINDER_SNOOZED: a duration or preset (see ResolveSnooze), with
+	// presets evaluated in Location (the user's timezone when nil).
+	Snooze   string
+	Location *time.Location
+
+	// ToEventID is the version a REVERTED event restores.
+	ToEventID *uint64
+
+	// ExpectedVersion rejects the write with *ConflictError when the
+	// projection has moved past it (optimistic concurrency). Nil = last write wins.
+	ExpectedVersion *uint64
+
+	// ExpectedVersions is ExpectedVersion for an If-Match list: the write
+	// goes through when the projection is at any of them.
+	ExpectedVersions []uint64
 }
 
 func (s *Service) CreateMemo(ctx context.Context, userID uint64, in CreateMemoInput) (uint64, error) {
 	var memoID uint64
 
 	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUserEvents(tx, userID); err != nil {
+			return err
+		}
 		m := Memo{UserID: userID}
 		if err := tx.Create(&m).Error; err != nil {
 			return err
 		}
 		memoID = m.ID
 
+		var proj MemoProjection
+
 		// CREATED event
-		if err := s.insertEvent(tx, memoID, userID, "CREATED", map[string]any{
-			"content": in.Content,
-		}, in.IdemKey); err != nil {
+		ev, err := s.insertEvent(tx, memoID, userID, "CREATED", CreatedPayload{Content: in.Content}, in.IdemKey)
+		if err != nil {
 			return err
 		}
-
-		// Projection
-		proj := MemoProjection{
-			MemoID:    memoID,
-			UserID:    userID,
-			Content:   in.Content,
-			Archived:  false,
-			RemindAt:  nil,
-			Tags:      pq.StringArray(ExtractTags(in.Content)),
-			Version:   0,
-			UpdatedAt: time.Now(),
-		}
-		if err := tx.Create(&proj).Error; err != nil {
+		if err := Apply(&proj, *ev); err != nil {
 			return err
 		}
 
-		// If remind_at provided: add event + update projection + enqueue job (atomic)
+		// If remind_at provided: add event + enqueue job (atomic)
 		if in.RemindAt != nil {
-			if err := s.insertEvent(tx, memoID, userID, "REMINDER_SET", map[string]any{
-				"remind_at": in.RemindAt.Format(time.RFC3339),
-			}, nil); err != nil {
+			rule, err := canonicalRule(in.RemindRule)
+			if err != nil {
+				return err
+			}
+			ev, err := s.insertEvent(tx, memoID, userID, "REMINDER_SET", ReminderSetPayload{
+				ReminderID: DefaultReminderID,
+				RemindAt:   *in.RemindAt,
+				Rule:       rule,
+			}, nil)
+			if err != nil {
+				return err
+			}
+			if err := Apply(&proj, *ev); err != nil {
 				return err
 			}
 
 			// enqueue job using SAME tx
-			payload, _ := json.Marshal(map[string]any{"memo_id": memoID})
-			j := jobs.Job{
-				UserID:  userID,
-				Type:    "REMINDER_DISPATCH",
-				Payload: payload,
-				RunAt:   *in.RemindAt,
-				Status:  "PENDING",
-			}
-			if err := tx.Create(&j).Error; err != nil {
+			if err := enqueueReminder(tx, userID, memoID, DefaultReminderID, *in.RemindAt); err != nil {
 				return err
 			}
-
-			proj.RemindAt = in.RemindAt
 		}
 
-		// set version to last event id (once at the end)
-		var last MemoEvent
-		if err := tx.Where("memo_id=? AND user_id=?", memoID, userID).Order("id desc").First(&last).Error; err != nil {
+		// Projection (version = last event id, set by Apply)
+		proj.UpdatedAt = time.Now()
+		if err := tx.Create(&proj).Error; err != nil {
 			return err
 		}
-
-		return tx.Model(&MemoProjection{}).
-			Where("memo_id=? AND user_id=?", memoID, userID).
-			Updates(map[string]any{
-				"remind_at":  proj.RemindAt,
-				"version":    last.ID,
-				"updated_at": time.Now(),
-			}).Error
+		return saveReminders(tx, proj)
 	})
 
 	return memoID, err
 }
 
-func (s *Service) AppendEvent(ctx context.Context, in AppendEventInput) error {
-	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+// AppendEvent returns the new projection version.
+func (s *Service) AppendEvent(ctx context.Context, in AppendEventInput) (uint64, error) {
+	var version uint64
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUserEvents(tx, in.UserID); err != nil {
+			return err
+		}
 		// ensure memo belongs to user
 		var m Memo
 		if err := tx.Where("id=? AND user_id=?", in.MemoID, in.UserID).First(&m).Error; err != nil {
@@ -119,93 +165,283 @@ func (s *Service) AppendEvent(ctx context.Context, in AppendEventInput) error {
 			return err
 		}
 
-		payload := map[string]any{}
+		// fetch projection FOR UPDATE (serializes writers per memo)
+		var p MemoProjection
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("memo_id=? AND user_id=?", in.MemoID, in.UserID).
+			First(&p).Error; err != nil {
+			return err
+		}
+		if err := LoadReminders(tx, &p); err != nil {
+			return err
+		}
+		if in.ExpectedVersion != nil && *in.ExpectedVersion != p.Version {
+			return &ConflictError{Expected: *in.ExpectedVersion, Current: p.Version}
+		}
+		if len(in.ExpectedVersions) > 0 && !slices.Contains(in.ExpectedVersions, p.Version) {
+			return &ConflictError{Expected: in.ExpectedVersions[0], Current: p.Version}
+		}
+
+		reminderID := in.ReminderID
+		if reminderID == "" {
+			reminderID = DefaultReminderID
+		}
+		if !ValidReminderID(reminderID) {
+			return ErrInvalidEvent
+		}
+		if err := s.states().Check(in.Type, &p); err != nil {
+			return err
+		}
+		if err := s.states().CheckReminder(in.Type, &p, reminderID); err != nil {
+			return err
+		}
+
+		var payload any
 		switch in.Type {
 		case "UPDATED":
 			if in.Content == nil {
 				return ErrInvalidEvent
 			}
-			payload["content"] = *in.Content
+			payload = UpdatedPayload{Content: *in.Content}
 		case "ARCHIVED", "RESTORED":
+			payload = EmptyPayload{}
+		case "REMINDER_CLEARED":
+			payload = ReminderClearedPayload{ReminderID: reminderID}
 		case "REMINDER_SET":
 			if in.RemindAt == nil {
 				return ErrInvalidEvent
 			}
-			payload["remind_at"] = in.RemindAt.Format(time.RFC3339)
-		case "REMINDER_CLEARED":
+			rule, err := canonicalRule(in.RemindRule)
+			if err != nil {
+				return err
+			}
+			payload = ReminderSetPayload{ReminderID: reminderID, RemindAt: *in.RemindAt, Rule: rule}
+		case "REMINDER_SNOOZED":
+			loc := in.Location
+			if loc == nil {
+				st, err := auth.LoadSettings(tx, in.UserID)
+				if err != nil {
+					return err
+				}
+				loc = st.Location()
+			}
+			until, err := ResolveSnooze(in.Snooze, time.Now(), loc)
+			if err != nil {
+				return err
+			}
+			payload = ReminderSnoozedPayload{
+				ReminderID: reminderID,
+				Snooze:     strings.ToLower(strings.TrimSpace(in.Snooze)),
+				Until:      until,
+			}
+		case "REVERTED":
+			if in.ToEventID == nil || *in.ToEventID >= p.Version {
+				return ErrInvalidEvent
+			}
+			target, err := s.revertTarget(tx, in.MemoID, in.UserID, *in.ToEventID)
+			if err != nil {
+				return err
+			}
+			reminders := target.Reminders
+			if reminders == nil {
+				reminders = []MemoReminder{}
+			}
+			payload = RevertedPayload{
+				ToEventID: *in.ToEventID,
+				Content:   target.Content,
+				Archived:  target.Archived,
+				Reminders: reminders,
+			}
 		default:
 			return ErrInvalidEvent
 		}
 
-		if err := s.insertEvent(tx, in.MemoID, in.UserID, in.Type, payload, in.IdemKey); err != nil {
+		ev, err := s.insertEvent(tx, in.MemoID, in.UserID, in.Type, payload, in.IdemKey)
+		if err != nil {
 			return err
 		}
 
-		// fetch projection FOR UPDATE
-		var p MemoProjection
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("memo_id=? AND user_id=?", in.MemoID, in.UserID).
-			First(&p).Error; err != nil {
-			return err
-		}
+		before := slices.Clone(p.Reminders)
+		prevContent := p.Content
 
-		switch in.Type {
-		case "UPDATED":
-			p.Content = *in.Content
-			p.Tags = pq.StringArray(ExtractTags(p.Content))
-		case "ARCHIVED":
-			p.Archived = true
-		case "RESTORED":
-			p.Archived = false
-		case "REMINDER_SET":
-			p.RemindAt = in.RemindAt
-		case "REMINDER_CLEARED":
-			p.RemindAt = nil
+		// version = last event id (set by Apply)
+		if err := Apply(&p, *ev); err != nil {
+			return err
 		}
 
-		// version = last event id
-		var last MemoEvent
-		if err := tx.Where("memo_id=? AND user_id=?", in.MemoID, in.UserID).Order("id desc").First(&last).Error; err != nil {
-			return err
+		// an UPDATED may carry a reminder detected in its content; editing
+		// other text around the same phrase does not set it again
+		detected := in.Type == "UPDATED" && in.RemindAt != nil && in.DetectedPhrase != "" &&
+			!strings.EqualFold(strings.TrimSpace(in.DetectedPhrase), ReminderPhrase(prevContent))
+		if detected {
+			if err := s.states().CheckReminder("REMINDER_SET", &p, reminderID); err != nil {
+				return err
+			}
+			ev, err := s.insertEvent(tx, in.MemoID, in.UserID, "REMINDER_SET", ReminderSetPayload{ReminderID: reminderID, RemindAt: *in.RemindAt}, nil)
+			if err != nil {
+				return err
+			}
+			if err := Apply(&p, *ev); err != nil {
+				return err
+			}
 		}
-		p.Version = last.ID
 		p.UpdatedAt = time.Now()
 
 		if err := tx.Save(&p).Error; err != nil {
 			return err
 		}
+		version = p.Version
 
-		// enqueue job when REMINDER_SET (atomic)
-		if in.Type == "REMINDER_SET" && in.RemindAt != nil {
-			payload, _ := json.Marshal(map[string]any{"memo_id": in.MemoID})
-			j := jobs.Job{
-				UserID:  in.UserID,
-				Type:    "REMINDER_DISPATCH",
-				Payload: payload,
-				RunAt:   *in.RemindAt,
-				Status:  "PENDING",
-			}
-			if err := tx.Create(&j).Error; err != nil {
+		touched := strings.HasPrefix(in.Type, "REMINDER_") || in.Type == "REVERTED" || detected
+		if touched {
+			if err := saveReminders(tx, p); err != nil {
 				return err
 			}
 		}
 
+		if err := s.maybeSnapshot(tx, p); err != nil {
+			return err
+		}
+
+		// keep REMINDER_DISPATCH jobs in line with the reminders (atomic)
+		switch {
+		case in.Type == "REVERTED":
+			return reconcileReverted(tx, in.UserID, in.MemoID, before, p.Reminders)
+		case touched:
+			var due *time.Time
+			if r := p.Reminder(reminderID); r != nil {
+				due = r.RemindAt
+			}
+			return reconcileReminderJobs(tx, in.UserID, in.MemoID, reminderID, due)
+		}
+
 		return nil
 	})
+
+	return version, err
 }
 
-func (s *Service) insertEvent(tx *gorm.DB, memoID, userID uint64, typ string, payload map[string]any, idem *string) error {
-	b, err := json.Marshal(payload)
+// canonicalRule validates an RRULE and returns its canonical form.
+func canonicalRule(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	r, err := rrule.Parse(s)
 	if err != nil {
+		return "", ErrInvalidEvent
+	}
+	return r.String(), nil
+}
+
+func (s *Service) states() *StateMachine {
+	if s.States != nil {
+		return s.States
+	}
+	return defaultStates
+}
+
+// revertTarget folds the memo up to toEventID, which must be one of its events.
+func (s *Service) revertTarget(tx *gorm.DB, memoID, userID, toEventID uint64) (MemoProjection, error) {
+	var ev MemoEvent
+	if err := tx.Where("id=? AND memo_id=? AND user_id=?", toEventID, memoID, userID).First(&ev).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return MemoProjection{}, ErrNotFound
+		}
+		return MemoProjection{}, err
+	}
+
+	p, _, err := foldAt(tx, memoID, userID, AsOf{Version: toEventID})
+	return p, err
+}
+
+// reconcileReminderJobs drops the pending dispatch jobs of one reminder and
+// enqueues one for remindAt, if set. Jobs without a reminder_id belong to
+// DefaultReminderID.
+func reconcileReminderJobs(tx *gorm.DB, userID, memoID uint64, reminderID string, remindAt *time.Time) error {
+	if err := tx.Exec(`
+		delete from jobs
+		where user_id = ?
+		  and type = 'REMINDER_DISPATCH'
+		  and status = 'PENDING'
+		  and (payload->>'memo_id')::bigint = ?
+		  and coalesce(payload->>'reminder_id', ?) = ?
+	`, userID, memoID, DefaultReminderID, reminderID).Error; err != nil {
 		return err
 	}
+	if remindAt == nil {
+		return nil
+	}
+	return enqueueReminder(tx, userID, memoID, reminderID, *remindAt)
+}
+
+// reconcileReverted reschedules every reminder whose time a REVERTED changed.
+// A restored reminder that already passed is not fired again.
+func reconcileReverted(tx *gorm.DB, userID, memoID uint64, before, after []MemoReminder) error {
+	at := func(rs []MemoReminder, id string) *time.Time {
+		for _, r := range rs {
+			if r.ReminderID == id {
+				return r.RemindAt
+			}
+		}
+		return nil
+	}
+	ids := map[string]bool{}
+	for _, r := range before {
+		ids[r.ReminderID] = true
+	}
+	for _, r := range after {
+		ids[r.ReminderID] = true
+	}
+	for id := range ids {
+		due := at(after, id)
+		if sameTime(at(before, id), due) {
+			continue
+		}
+		if due != nil && due.Before(time.Now()) {
+			due = nil
+		}
+		if err := reconcileReminderJobs(tx, userID, memoID, id, due); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func enqueueReminder(tx *gorm.DB, userID, memoID uint64, reminderID string, runAt time.Time) error {
+	payload, _ := json.Marshal(map[string]any{"memo_id": memoID, "reminder_id": reminderID})
+	j := jobs.Job{
+		UserID:  userID,
+		Type:    "REMINDER_DISPATCH",
+		Payload: payload,
+		RunAt:   runAt,
+		Status:  "PENDING",
+	}
+	return tx.Create(&j).Error
+}
+
+func (s *Service) insertEvent(tx *gorm.DB, memoID, userID uint64, typ string, payload any, idem *string) (*MemoEvent, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
 	ev := MemoEvent{
 		MemoID:         memoID,
 		UserID:         userID,
 		Type:           typ,
 		Payload:        json.RawMessage(b),
+		SchemaVersion:  CurrentSchemaVersion(typ),
 		IdempotencyKey: idem,
 		CreatedAt:      time.Now(),
 	}
-	return tx.Create(&ev).Error
+	if err := tx.Create(&ev).Error; err != nil {
+		return nil, err
+	}
+	// delivered to listeners on commit
+	if err := notifyEvent(tx, &ev); err != nil {
+		return nil, err
+	}
+	if err := enqueueWebhooks(tx, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
 }
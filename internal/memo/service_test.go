@@ -0,0 +1,116 @@
+package memo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tell/internal/jobs"
+)
+
+func TestUpdatedDetectedReminder(t *testing.T) {
+	db := testDB(t)
+	s := &Service{DB: db}
+	ctx := context.Background()
+	uid := testUser()
+
+	memoID, err := s.CreateMemo(ctx, uid, CreateMemoInput{Content: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reminderSets := func() int64 {
+		var n int64
+		if err := db.Model(&MemoEvent{}).Where("memo_id = ? and type = 'REMINDER_SET'", memoID).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	update := func(content, phrase string, at *time.Time) {
+		t.Helper()
+		if _, err := s.AppendEvent(ctx, AppendEventInput{MemoID: memoID, UserID: uid, Type: "UPDATED", Content: &content, RemindAt: at, DetectedPhrase: phrase}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	at := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	// explicit remind_at without a detected phrase is ignored
+	update("b", "", &at)
+	if n := reminderSets(); n != 0 {
+		t.Fatalf("%d REMINDER_SET after a plain remind_at", n)
+	}
+
+	update("beli susu besok jam 9", "besok jam 9", &at)
+	if n := reminderSets(); n != 1 {
+		t.Fatalf("%d REMINDER_SET after a new phrase, want 1", n)
+	}
+
+	// same phrase, other text: no new REMINDER_SET
+	later := at.Add(time.Minute)
+	update("beli susu dan roti besok jam 9", "besok jam 9", &later)
+	if n := reminderSets(); n != 1 {
+		t.Fatalf("%d REMINDER_SET after an unrelated edit, want 1", n)
+	}
+
+	update("beli susu dan roti lusa", "lusa", &later)
+	if n := reminderSets(); n != 2 {
+		t.Fatalf("%d REMINDER_SET after a changed phrase, want 2", n)
+	}
+}
+
+func TestRevertAfterFire(t *testing.T) {
+	db := testDB(t)
+	s := &Service{DB: db}
+	ctx := context.Background()
+	uid := testUser()
+
+	memoID, err := s.CreateMemo(ctx, uid, CreateMemoInput{Content: "rapat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+	v, err := s.AppendEvent(ctx, AppendEventInput{MemoID: memoID, UserID: uid, Type: "REMINDER_SET", RemindAt: &at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecordReminderFired(ctx, jobs.ReminderFired{UserID: uid, MemoID: memoID, JobID: uid, FiredAt: at}); err != nil {
+		t.Fatal(err)
+	}
+	content := "rapat besar"
+	if _, err := s.AppendEvent(ctx, AppendEventInput{MemoID: memoID, UserID: uid, Type: "UPDATED", Content: &content}); err != nil {
+		t.Fatal(err)
+	}
+	// the worker would mark the fired job done; only new jobs matter here
+	dispatchJobs := func() int64 {
+		var n int64
+		if err := db.Model(&jobs.Job{}).Where("user_id = ? and type = 'REMINDER_DISPATCH'", uid).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	before := dispatchJobs()
+	if _, err := s.AppendEvent(ctx, AppendEventInput{MemoID: memoID, UserID: uid, Type: "REVERTED", ToEventID: &v}); err != nil {
+		t.Fatal(err)
+	}
+
+	var p MemoProjection
+	if err := db.Where("memo_id = ?", memoID).First(&p).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadReminders(db, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Content != "rapat" {
+		t.Fatalf("content %q", p.Content)
+	}
+	r := p.Reminder(DefaultReminderID)
+	if r == nil || r.Status != "FIRED" || r.RemindAt != nil || r.FiredCount != 1 || r.LastFiredAt == nil || !r.LastFiredAt.Equal(at) {
+		t.Fatalf("reminder after revert %+v", r)
+	}
+	if p.LastFiredAt == nil || !p.LastFiredAt.Equal(at) {
+		t.Fatalf("last_fired_at %v", p.LastFiredAt)
+	}
+
+	if n := dispatchJobs(); n != before {
+		t.Fatalf("%d dispatch jobs after reverting a fired reminder, want %d", n, before)
+	}
+}
@@ -0,0 +1,171 @@
+package memo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProjectionRev is the revision of the fold rules in Apply. Any change to
+// Apply (or to an upcaster it relies on) must bump it: snapshots of another
+// revision are ignored, while a stale one of this revision would be read as
+// the truth by point-in-time reads and replay -from-snapshots.
+const ProjectionRev = 6
+
+// DefaultSnapshotEvery is used when Service.SnapshotEvery is 0.
+const DefaultSnapshotEvery = 100
+
+// foldAt folds the memo up to at, starting from the nearest usable snapshot.
+// ok is false when no event matches (memo did not exist yet).
+func foldAt(db *gorm.DB, memoID, userID uint64, at AsOf) (p MemoProjection, ok bool, err error) {
+	var after uint64
+
+	snap, err := nearestSnapshot(db, memoID, userID, at)
+	if err != nil {
+		return MemoProjection{}, false, err
+	}
+	if snap != nil {
+		if err := json.Unmarshal(snap.State, &p); err != nil {
+			return MemoProjection{}, false, err
+		}
+		after = snap.Version
+		ok = true
+	}
+
+	evs, err := loadEvents(db, memoID, userID, after, at)
+	if err != nil {
+		return MemoProjection{}, false, err
+	}
+	for _, ev := range evs {
+		if err := Apply(&p, ev); err != nil {
+			return MemoProjection{}, false, err
+		}
+		ok = true
+	}
+	return p, ok, nil
+}
+
+func nearestSnapshot(db *gorm.DB, memoID, userID uint64, at AsOf) (*MemoSnapshot, error) {
+	q := db.Where("memo_id=? AND user_id=? AND rev=?", memoID, userID, ProjectionRev)
+	if at.Version != 0 {
+		q = q.Where("version <= ?", at.Version)
+	}
+	if at.Time != nil {
+		q = q.Where("at <= ?", *at.Time)
+	}
+
+	var snap MemoSnapshot
+	if err := q.Order("version desc").First(&snap).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &snap, nil
+}
+
+func (s *Service) snapshotEvery() int {
+	if s.SnapshotEvery > 0 {
+		return s.SnapshotEvery
+	}
+	return DefaultSnapshotEvery
+}
+
+// maybeSnapshot writes a snapshot of p once SnapshotEvery events have
+// accumulated since the last one. Runs in the write tx.
+func (s *Service) maybeSnapshot(tx *gorm.DB, p MemoProjection) error {
+	var n int64
+	if err := tx.Raw(`
+		select count(*)
+		from memo_events
+		where memo_id = ?
+		  and id > coalesce((select max(version) from memo_snapshots where memo_id = ? and rev = ?), 0)
+	`, p.MemoID, p.MemoID, ProjectionRev).Scan(&n).Error; err != nil {
+		return err
+	}
+	if n < int64(s.snapshotEvery()) {
+		return nil
+	}
+	return writeSnapshot(tx, p)
+}
+
+func writeSnapshot(tx *gorm.DB, p MemoProjection) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	// At is the time of the event at Version, which time-based reads
+	// compare with; p.UpdatedAt is when the projection row was written
+	var at time.Time
+	if err := tx.Model(&MemoEvent{}).Select("created_at").Where("id = ?", p.Version).Scan(&at).Error; err != nil {
+		return err
+	}
+	snap := MemoSnapshot{
+		MemoID:  p.MemoID,
+		UserID:  p.UserID,
+		Version: p.Version,
+		Rev:     ProjectionRev,
+		At:      at,
+		State:   b,
+	}
+	return tx.Create(&snap).Error
+}
+
+// BackfillSnapshots writes the missing snapshots for one memo, or for every
+// memo of the user when memoID is 0. Each memo gets its own tx.
+func (s *Service) BackfillSnapshots(ctx context.Context, userID, memoID uint64) error {
+	db := s.DB.WithContext(ctx)
+
+	q := db.Model(&Memo{}).Where("user_id = ?", userID)
+	if memoID != 0 {
+		q = q.Where("id = ?", memoID)
+	}
+	var ids []uint64
+	if err := q.Order("id asc").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	every := s.snapshotEvery()
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var p MemoProjection
+			var after uint64
+			snap, err := nearestSnapshot(tx, id, userID, AsOf{})
+			if err != nil {
+				return err
+			}
+			if snap != nil {
+				if err := json.Unmarshal(snap.State, &p); err != nil {
+					return err
+				}
+				after = snap.Version
+			}
+
+			evs, err := loadEvents(tx, id, userID, after, AsOf{})
+			if err != nil {
+				return err
+			}
+			for i, ev := range evs {
+				if err := Apply(&p, ev); err != nil {
+					return err
+				}
+				if (i+1)%every == 0 {
+					if err := writeSnapshot(tx, p); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
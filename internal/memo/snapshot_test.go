@@ -0,0 +1,35 @@
+package memo
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSnapshotAtIsEventTime(t *testing.T) {
+	db := testDB(t)
+	s := &Service{DB: db, SnapshotEvery: 2}
+	ctx := context.Background()
+	uid := testUser()
+
+	memoID, err := s.CreateMemo(ctx, uid, CreateMemoInput{Content: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []string{"b", "c"} {
+		if _, err := s.AppendEvent(ctx, AppendEventInput{MemoID: memoID, UserID: uid, Type: "UPDATED", Content: &c}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var snap MemoSnapshot
+	if err := db.Where("memo_id = ?", memoID).Order("version desc").First(&snap).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ev MemoEvent
+	if err := db.First(&ev, snap.Version).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !snap.At.Equal(ev.CreatedAt) {
+		t.Fatalf("snapshot at %v, event %d created at %v", snap.At, ev.ID, ev.CreatedAt)
+	}
+}
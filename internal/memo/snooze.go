@@ -0,0 +1,35 @@
+package memo
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxSnooze bounds duration snoozes; longer delays should be a new REMINDER_SET.
+const MaxSnooze = 7 * 24 * time.Hour
+
+// MorningHour is when "tomorrow_morning" fires, in the user's zone.
+const MorningHour = 9
+
+// ResolveSnooze turns a snooze spec into an absolute time: a Go duration
+// ("10m", "1h30m") or a preset ("tomorrow_morning"). Presets are
+// evaluated in loc, which AppendEvent fills with the user's settings
+// timezone when the request names none; a nil loc is read as UTC.
+func ResolveSnooze(spec string, now time.Time, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	spec = strings.ToLower(strings.TrimSpace(spec))
+
+	switch spec {
+	case "tomorrow_morning", "tomorrow":
+		y, m, d := now.In(loc).Date()
+		return time.Date(y, m, d+1, MorningHour, 0, 0, 0, loc), nil
+	}
+
+	dur, err := time.ParseDuration(spec)
+	if err != nil || dur <= 0 || dur > MaxSnooze {
+		return time.Time{}, ErrInvalidEvent
+	}
+	return now.Add(dur), nil
+}
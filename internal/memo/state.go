@@ -0,0 +1,128 @@
+package memo
+
+// StateError is a rejected transition. Code is stable and machine-readable.
+type StateError struct {
+	Code string
+	Msg  string
+}
+
+func (e *StateError) Error() string { return e.Msg }
+
+var (
+	ErrAlreadyArchived  = &StateError{Code: "already_archived", Msg: "memo already archived"}
+	ErrNotArchived      = &StateError{Code: "not_archived", Msg: "memo is not archived"}
+	ErrMemoArchived     = &StateError{Code: "memo_archived", Msg: "memo is archived"}
+	ErrNoReminder       = &StateError{Code: "no_reminder", Msg: "memo has no reminder"}
+	ErrNotFired         = &StateError{Code: "not_fired", Msg: "reminder has not fired yet"}
+	ErrTooManyReminders = &StateError{Code: "too_many_reminders", Msg: "memo has too many reminders"}
+)
+
+// Rule decides whether an event type may be applied to the current state.
+type Rule func(p *MemoProjection) error
+
+// ReminderRule checks a REMINDER_* event against the reminder it names;
+// r is nil when the memo has no reminder with that id.
+type ReminderRule func(p *MemoProjection, r *MemoReminder) error
+
+// StateMachine validates events against the projection before they are
+// written, so no-op events never reach the log. Types without a rule are
+// always allowed.
+type StateMachine struct {
+	rules         map[string]Rule
+	reminderRules map[string]ReminderRule
+}
+
+// NewStateMachine returns the default rules.
+func NewStateMachine() *StateMachine {
+	return &StateMachine{rules: map[string]Rule{
+		"UPDATED": func(p *MemoProjection) error {
+			if p.Archived {
+				return ErrMemoArchived
+			}
+			return nil
+		},
+		"ARCHIVED": func(p *MemoProjection) error {
+			if p.Archived {
+				return ErrAlreadyArchived
+			}
+			return nil
+		},
+		"RESTORED": func(p *MemoProjection) error {
+			if !p.Archived {
+				return ErrNotArchived
+			}
+			return nil
+		},
+		"REMINDER_SET": func(p *MemoProjection) error {
+			if p.Archived {
+				return ErrMemoArchived
+			}
+			return nil
+		},
+		"REMINDER_SNOOZED": func(p *MemoProjection) error {
+			if p.Archived {
+				return ErrMemoArchived
+			}
+			return nil
+		},
+	}, reminderRules: map[string]ReminderRule{
+		"REMINDER_SET": func(p *MemoProjection, r *MemoReminder) error {
+			if r == nil && len(p.Reminders) >= MaxReminders {
+				return ErrTooManyReminders
+			}
+			return nil
+		},
+		"REMINDER_CLEARED": func(_ *MemoProjection, r *MemoReminder) error {
+			if r == nil {
+				return ErrNoReminder
+			}
+			return nil
+		},
+		"REMINDER_SNOOZED": func(_ *MemoProjection, r *MemoReminder) error {
+			switch {
+			case r == nil:
+				return ErrNoReminder
+			case r.FiredCount == 0:
+				return ErrNotFired
+			}
+			return nil
+		},
+	}}
+}
+
+// Set replaces the rule for typ. A nil rule allows typ unconditionally.
+func (m *StateMachine) Set(typ string, r Rule) {
+	if r == nil {
+		delete(m.rules, typ)
+		return
+	}
+	m.rules[typ] = r
+}
+
+// SetReminder replaces the reminder rule for typ; nil removes it.
+func (m *StateMachine) SetReminder(typ string, r ReminderRule) {
+	if r == nil {
+		delete(m.reminderRules, typ)
+		return
+	}
+	m.reminderRules[typ] = r
+}
+
+func (m *StateMachine) Check(typ string, p *MemoProjection) error {
+	r, ok := m.rules[typ]
+	if !ok {
+		return nil
+	}
+	return r(p)
+}
+
+// CheckReminder runs typ's reminder rule against reminder id of p.
+func (m *StateMachine) CheckReminder(typ string, p *MemoProjection, id string) error {
+	r, ok := m.reminderRules[typ]
+	if !ok {
+		return nil
+	}
+	return r(p, p.Reminder(id))
+}
+
+var defaultStates = NewStateMachine()
@@ -0,0 +1,73 @@
+package memo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStateMachine(t *testing.T) {
+	fired := MemoReminder{ReminderID: "r", Status: "FIRED", FiredCount: 1}
+	pending := MemoReminder{ReminderID: "r", Status: "PENDING"}
+	full := make([]MemoReminder, MaxReminders)
+	for i := range full {
+		full[i].ReminderID = fmt.Sprintf("r%d", i)
+	}
+
+	live := MemoProjection{}
+	archived := MemoProjection{Archived: true}
+	withFired := MemoProjection{Reminders: []MemoReminder{fired}}
+	withPending := MemoProjection{Reminders: []MemoReminder{pending}}
+	atLimit := MemoProjection{Reminders: full}
+
+	tests := []struct {
+		typ  string
+		p    MemoProjection
+		id   string
+		want string // StateError code, "" = allowed
+	}{
+		{"ARCHIVED", live, "", ""},
+		{"ARCHIVED", archived, "", "already_archived"},
+		{"RESTORED", archived, "", ""},
+		{"RESTORED", live, "", "not_archived"},
+		{"UPDATED", live, "", ""},
+		{"UPDATED", archived, "", "memo_archived"},
+		{"REMINDER_SET", archived, "r", "memo_archived"},
+		{"REMINDER_SET", live, "r", ""},
+		{"REMINDER_SET", atLimit, "new", "too_many_reminders"},
+		{"REMINDER_SET", atLimit, "r0", ""}, // replacing one is fine
+		{"REMINDER_CLEARED", live, "r", "no_reminder"},
+		{"REMINDER_CLEARED", withPending, "r", ""},
+		{"REMINDER_SNOOZED", live, "r", "no_reminder"},
+		{"REMINDER_SNOOZED", withPending, "r", "not_fired"},
+		{"REMINDER_SNOOZED", withFired, "r", ""},
+		{"REMINDER_SNOOZED", MemoProjection{Archived: true, Reminders: []MemoReminder{fired}}, "r", "memo_archived"},
+		{"CREATED", archived, "", ""}, // no rule
+	}
+
+	m := NewStateMachine()
+	for _, tt := range tests {
+		p := tt.p
+		err := m.Check(tt.typ, &p)
+		if err == nil {
+			err = m.CheckReminder(tt.typ, &p, tt.id)
+		}
+		got := ""
+		if se, ok := err.(*StateError); ok {
+			got = se.Code
+		} else if err != nil {
+			t.Fatalf("%s: not a StateError: %v", tt.typ, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s (archived=%v, %d reminders, id %q) = %q, want %q", tt.typ, p.Archived, len(p.Reminders), tt.id, got, tt.want)
+		}
+	}
+
+	m.Set("ARCHIVED", nil)
+	if err := m.Check("ARCHIVED", &archived); err != nil {
+		t.Fatalf("rule removed, still %v", err)
+	}
+	m.SetReminder("REMINDER_SNOOZED", nil)
+	if err := m.CheckReminder("REMINDER_SNOOZED", &withPending, "r"); err != nil {
+		t.Fatalf("reminder rule removed, still %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package memo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SyncEvent is an event queued by an offline client, tagged with the
+// version it was based on.
+type SyncEvent struct {
+	ClientID string // opaque, echoed back in the result
+	MemoID   uint64 // 0 with Type CREATED = new memo
+
+	// MemoClientID names a memo by the ClientID of the CREATED that made it
+	// earlier in the batch, for clients that have no server id yet.
+	MemoClientID string
+
+	Type        string
+	Content     *string
+	RemindAt    *time.Time
+	RemindRule  string
+	Snooze      string
+	ReminderID  string
+	Location    *time.Location // snooze presets; nil = user's timezone
+	ToEventID   *uint64
+	BaseVersion *uint64
+	IdemKey     *string
+}
+
+type SyncInput struct {
+	UserID uint64
+	Cursor uint64 // last event id the client has seen
+	Events []SyncEvent
+	Limit  int // max server events returned (0 = 500)
+}
+
+// Sync result statuses.
+const (
+	SyncApplied      = "applied"       // applied as sent
+	SyncMerged       = "merged"        // UPDATED merged with concurrent edits
+	SyncConflictCopy = "conflict_copy" // merge failed; content saved as a new memo
+	SyncDuplicate    = "duplicate"     // idempotency key already applied
+	SyncRejected     = "rejected"      // see Code
+)
+
+type SyncResult struct {
+	ClientID   string
+	Status     string
+	MemoID     uint64
+	Version    uint64
+	ConflictOf uint64 // original memo of a conflict copy
+	Code       string // rejected: not_found, invalid_event or a StateError code
+}
+
+type SyncOutput struct {
+	Results    []SyncResult
+	Events     []MemoEvent // server events after the client's cursor
+	NextCursor uint64
+	HasMore    bool
+}
+
+// Sync applies a batch of client events in order and returns every server
+// event since in.Cursor. A stale UPDATED is three-way merged against the
+// client's base version; other stale events are applied last-write-wins
+// (the state machine still guards them).
+//
+// The batch runs in one transaction: a rejected event only rolls back its
+// own savepoint, any other error rolls back the whole batch.
+func (s *Service) Sync(ctx context.Context, in SyncInput) (SyncOutput, error) {
+	var out SyncOutput
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUserEvents(tx, in.UserID); err != nil {
+			return err
+		}
+		ts := *s
+		ts.DB = tx // nested transactions become savepoints
+
+		created := map[string]uint64{} // CREATED client_id -> memo id
+		for _, ev := range in.Events {
+			res, err := ts.syncResolved(ctx, in.UserID, ev, created)
+			if err != nil {
+				return err
+			}
+			res.ClientID = ev.ClientID
+			out.Results = append(out.Results, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return SyncOutput{}, err
+	}
+
+	limit := in.Limit
+	if limit <= 0 {
+		limit = 500
+	}
+	evs, err := EventsAfter(s.DB.WithContext(ctx), in.UserID, in.Cursor, limit+1)
+	if err != nil {
+		return out, err
+	}
+	if len(evs) > limit {
+		evs = evs[:limit]
+		out.HasMore = true
+	}
+	out.Events = evs
+	out.NextCursor = in.Cursor
+	if n := len(evs); n > 0 {
+		out.NextCursor = evs[n-1].ID
+	}
+	return out, nil
+}
+
+// syncResolved maps MemoClientID through created before syncOne, and
+// records the memo of a CREATED under its ClientID.
+func (s *Service) syncResolved(ctx context.Context, userID uint64, ev SyncEvent, created map[string]uint64) (SyncResult, error) {
+	if ev.MemoClientID != "" {
+		id, ok := created[ev.MemoClientID]
+		if ev.MemoID != 0 || !ok {
+			return SyncResult{Status: SyncRejected, MemoID: ev.MemoID, Code: "not_found"}, nil
+		}
+		ev.MemoID = id
+	}
+	isCreate := ev.MemoID == 0 && ev.Type == "CREATED"
+	if _, dup := created[ev.ClientID]; isCreate && ev.ClientID != "" && dup {
+		return SyncResult{Status: SyncRejected, Code: "invalid_event"}, nil
+	}
+
+	res, err := s.syncOne(ctx, userID, ev)
+	if err == nil && isCreate && ev.ClientID != "" && (res.Status == SyncApplied || res.Status == SyncDuplicate) {
+		created[ev.ClientID] = res.MemoID
+	}
+	return res, err
+}
+
+func (s *Service) syncOne(ctx context.Context, userID uint64, ev SyncEvent) (SyncResult, error) {
+	if ev.IdemKey != nil {
+		var prev MemoEvent
+		err := s.DB.WithContext(ctx).Where("user_id=? AND idempotency_key=?", userID, *ev.IdemKey).First(&prev).Error
+		if err == nil {
+			return SyncResult{Status: SyncDuplicate, MemoID: prev.MemoID, Version: prev.ID}, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return SyncResult{}, err
+		}
+	}
+
+	if ev.MemoID == 0 {
+		if ev.Type != "CREATED" || ev.Content == nil {
+			return SyncResult{Status: SyncRejected, Code: "invalid_event"}, nil
+		}
+		id, err := s.CreateMemo(ctx, userID, CreateMemoInput{Content: *ev.Content, RemindAt: ev.RemindAt, RemindRule: ev.RemindRule, IdemKey: ev.IdemKey})
+		if err != nil {
+			return rejectOrFail(0, err)
+		}
+		return SyncResult{Status: SyncApplied, MemoID: id, Version: s.currentVersion(ctx, id)}, nil
+	}
+
+	in := AppendEventInput{
+		MemoID:          ev.MemoID,
+		UserID:          userID,
+		Type:            ev.Type,
+		Content:         ev.Content,
+		RemindAt:        ev.RemindAt,
+		RemindRule:      ev.RemindRule,
+		Snooze:          ev.Snooze,
+		ReminderID:      ev.ReminderID,
+		Location:        ev.Location,
+		IdemKey:         ev.IdemKey,
+		ToEventID:       ev.ToEventID,
+		ExpectedVersion: ev.BaseVersion,
+	}
+	v, err := s.AppendEvent(ctx, in)
+	if err == nil {
+		return SyncResult{Status: SyncApplied, MemoID: ev.MemoID, Version: v}, nil
+	}
+
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		return rejectOrFail(ev.MemoID, err)
+	}
+
+	if ev.Type != "UPDATED" || ev.Content == nil || ev.BaseVersion == nil {
+		in.ExpectedVersion = nil
+		v, err := s.AppendEvent(ctx, in)
+		if err != nil {
+			return rejectOrFail(ev.MemoID, err)
+		}
+		return SyncResult{Status: SyncApplied, MemoID: ev.MemoID, Version: v}, nil
+	}
+
+	return s.mergeUpdate(ctx, userID, ev, in)
+}
+
+// mergeUpdate merges a stale UPDATED against the current content, or saves
+// the client's content as a conflict copy. Sync holds the user's event lock,
+// so the memo cannot move between reading it and appending the merge.
+func (s *Service) mergeUpdate(ctx context.Context, userID uint64, ev SyncEvent, in AppendEventInput) (SyncResult, error) {
+	base, err := s.StateAt(ctx, userID, ev.MemoID, AsOf{Version: *ev.BaseVersion})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return SyncResult{}, err
+	}
+	if err == nil {
+		cur, err := s.StateAt(ctx, userID, ev.MemoID, AsOf{})
+		if err != nil {
+			return rejectOrFail(ev.MemoID, err)
+		}
+		if merged, ok := Merge3(base.Content, *ev.Content, cur.Content); ok {
+			in.Content = &merged
+			in.ExpectedVersion = &cur.Version
+			v, err := s.AppendEvent(ctx, in)
+			if err != nil {
+				return rejectOrFail(ev.MemoID, err)
+			}
+			return SyncResult{Status: SyncMerged, MemoID: ev.MemoID, Version: v}, nil
+		}
+	}
+
+	id, err := s.CreateMemo(ctx, userID, CreateMemoInput{Content: *ev.Content, IdemKey: ev.IdemKey})
+	if err != nil {
+		return SyncResult{}, err
+	}
+	return SyncResult{Status: SyncConflictCopy, MemoID: id, Version: s.currentVersion(ctx, id), ConflictOf: ev.MemoID}, nil
+}
+
+func rejectOrFail(memoID uint64, err error) (SyncResult, error) {
+	var stateErr *StateError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return SyncResult{Status: SyncRejected, MemoID: memoID, Code: "not_found"}, nil
+	case errors.Is(err, ErrInvalidEvent):
+		return SyncResult{Status: SyncRejected, MemoID: memoID, Code: "invalid_event"}, nil
+	case errors.As(err, &stateErr):
+		return SyncResult{Status: SyncRejected, MemoID: memoID, Code: stateErr.Code}, nil
+	}
+	return SyncResult{}, err
+}
+
+func (s *Service) currentVersion(ctx context.Context, memoID uint64) uint64 {
+	var p MemoProjection
+	if err := s.DB.WithContext(ctx).Select("version").Where("memo_id = ?", memoID).First(&p).Error; err != nil {
+		return 0
+	}
+	return p.Version
+}
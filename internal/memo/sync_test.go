@@ -0,0 +1,67 @@
+package memo
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSyncClientIDs(t *testing.T) {
+	db := testDB(t)
+	s := &Service{DB: db}
+	ctx := context.Background()
+	uid := testUser()
+	str := func(v string) *string { return &v }
+
+	out, err := s.Sync(ctx, SyncInput{UserID: uid, Events: []SyncEvent{
+		{ClientID: "c1", Type: "CREATED", Content: str("offline")},
+		{ClientID: "c2", MemoClientID: "c1", Type: "UPDATED", Content: str("offline, edited")},
+		{ClientID: "c3", MemoClientID: "nope", Type: "ARCHIVED"},
+		{ClientID: "c4", MemoClientID: "c1", Type: "ARCHIVED"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{SyncApplied, SyncApplied, SyncRejected, SyncApplied}
+	for i, r := range out.Results {
+		if r.Status != want[i] {
+			t.Fatalf("result %d: %+v, want %s", i, r, want[i])
+		}
+	}
+	id := out.Results[0].MemoID
+	if out.Results[1].MemoID != id || out.Results[3].MemoID != id {
+		t.Fatalf("client id not resolved: %+v", out.Results)
+	}
+
+	st, err := s.StateAt(ctx, uid, id, AsOf{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Content != "offline, edited" || !st.Archived {
+		t.Fatalf("state %+v", st)
+	}
+}
+
+// A failing event rolls back the whole batch, not just itself.
+func TestSyncAtomic(t *testing.T) {
+	db := testDB(t)
+	s := &Service{DB: db}
+	ctx := context.Background()
+	uid := testUser()
+	str := func(v string) *string { return &v }
+
+	_, err := s.Sync(ctx, SyncInput{UserID: uid, Events: []SyncEvent{
+		{ClientID: "c1", Type: "CREATED", Content: str("kept?")},
+		{ClientID: "c2", MemoClientID: "c1", Type: "UPDATED", Content: str("nul \x00 byte")}, // text cannot hold NUL
+	}})
+	if err == nil {
+		t.Fatal("batch with a failing event succeeded")
+	}
+
+	var n int64
+	if err := db.Model(&Memo{}).Where("user_id = ?", uid).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("%d memos left behind by a failed batch", n)
+	}
+}
@@ -0,0 +1,108 @@
+package memo
+
+import (
+	"sort"
+	"time"
+
+	"tell/internal/rrule"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// MaxOccurrences caps the result of Upcoming.
+const MaxOccurrences = 500
+
+// Occurrence is one upcoming run of a reminder.
+type Occurrence struct {
+	MemoID     uint64
+	ReminderID string
+	At         time.Time
+	Content    string
+	Tags       []string
+	Rule       string
+	Snoozed    bool // At is a snooze, not an occurrence of Rule
+}
+
+// PendingReminder is a due reminder of an unarchived memo, with the memo.
+type PendingReminder struct {
+	MemoReminder `gorm:"embedded"`
+	Content      string
+	Tags         pq.StringArray `gorm:"type:text[]"`
+	UpdatedAt    time.Time      // of the memo
+}
+
+// PendingReminders lists all of the user's PendingReminders.
+func PendingReminders(db *gorm.DB, userID uint64) ([]PendingReminder, error) {
+	var rows []PendingReminder
+	err := pendingReminders(db, userID).Order("r.memo_id asc, r.reminder_id asc").Scan(&rows).Error
+	return rows, err
+}
+
+func pendingReminders(db *gorm.DB, userID uint64) *gorm.DB {
+	return db.Table("memo_reminders r").
+		Select("r.*, p.content, p.tags, p.updated_at").
+		Joins("join memo_projections p on p.memo_id = r.memo_id").
+		Where("r.user_id = ? AND p.archived = false AND r.remind_at is not null", userID)
+}
+
+// Upcoming lists the due reminders of the user's unarchived memos in
+// [from, to), sorted by time. Recurring reminders are expanded in loc, so
+// one reminder can occur several times.
+func Upcoming(db *gorm.DB, userID uint64, from, to time.Time, loc *time.Location) ([]Occurrence, error) {
+	var rows []PendingReminder
+	if err := pendingReminders(db, userID).
+		Where("r.remind_at < ?", to).
+		Where("r.remind_at >= ? OR r.rule <> ''", from).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var out []Occurrence
+	for _, row := range rows {
+		r := row.MemoReminder
+		occ := Occurrence{
+			MemoID:     r.MemoID,
+			ReminderID: r.ReminderID,
+			Content:    row.Content,
+			Tags:       []string(row.Tags),
+			Rule:       r.Rule,
+			Snoozed:    r.Status == "SNOOZED",
+		}
+		if !r.RemindAt.Before(from) {
+			occ.At = *r.RemindAt
+			out = append(out, occ)
+		}
+		if r.Rule == "" || r.Start == nil {
+			continue
+		}
+		rule, err := rrule.Parse(r.Rule)
+		if err != nil {
+			continue
+		}
+		// RemindAt is the pending run; the series goes on after it
+		after := r.RemindAt.Add(time.Nanosecond)
+		if from.After(after) {
+			after = from
+		}
+		occ.Snoozed = false
+		for _, t := range rule.Between(r.Start.In(loc), after, to, MaxOccurrences) {
+			occ.At = t
+			out = append(out, occ)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].At.Equal(out[j].At) {
+			return out[i].At.Before(out[j].At)
+		}
+		if out[i].MemoID != out[j].MemoID {
+			return out[i].MemoID < out[j].MemoID
+		}
+		return out[i].ReminderID < out[j].ReminderID
+	})
+	if len(out) > MaxOccurrences {
+		out = out[:MaxOccurrences]
+	}
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package memo
+
+import (
+	"encoding/json"
+	"time"
+
+	"tell/internal/jobs"
+	"tell/internal/webhook"
+
+	"gorm.io/gorm"
+)
+
+// enqueueWebhooks writes one WEBHOOK_DELIVERY job per matching subscription
+// in the event's own tx, so the jobs table doubles as the outbox: a
+// delivery exists if and only if the event committed.
+func enqueueWebhooks(tx *gorm.DB, ev *MemoEvent) error {
+	var subs []webhook.Subscription
+	if err := tx.Where("user_id = ? AND active = true", ev.UserID).Find(&subs).Error; err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	// body is frozen at write time; retries send the same bytes
+	body, err := json.Marshal(map[string]any{
+		"id":             ev.ID,
+		"memo_id":        ev.MemoID,
+		"user_id":        ev.UserID,
+		"type":           ev.Type,
+		"payload":        ev.Payload,
+		"schema_version": ev.SchemaVersion,
+		"created_at":     ev.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		if !sub.Matches(ev.Type) {
+			continue
+		}
+		payload, _ := json.Marshal(map[string]any{
+			"subscription_id": sub.ID,
+			"event_id":        ev.ID,
+			"event_type":      ev.Type,
+			"body":            json.RawMessage(body),
+		})
+		j := jobs.Job{
+			UserID:  ev.UserID,
+			Type:    "WEBHOOK_DELIVERY",
+			Payload: payload,
+			RunAt:   time.Now(),
+			Status:  "PENDING",
+		}
+		if err := tx.Create(&j).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
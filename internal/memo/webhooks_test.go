@@ -0,0 +1,76 @@
+package memo
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"tell/internal/jobs"
+	"tell/internal/webhook"
+)
+
+func TestWebhookOutbox(t *testing.T) {
+	db := testDB(t)
+	s := &Service{DB: db}
+	ctx := context.Background()
+	uid := testUser()
+
+	all := webhook.Subscription{UserID: uid, URL: "https://example.com/all", Secret: "s", Active: true}
+	archives := webhook.Subscription{UserID: uid, URL: "https://example.com/archived", Secret: "s", Active: true, EventTypes: []string{"ARCHIVED"}}
+	off := webhook.Subscription{UserID: uid, URL: "https://example.com/off", Secret: "s", Active: true}
+	for _, sub := range []*webhook.Subscription{&all, &archives, &off} {
+		if err := db.Create(sub).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the column default would turn a false Active back on at insert
+	if err := db.Model(&off).Update("active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := func() map[uint64][]string {
+		var js []jobs.Job
+		if err := db.Where("user_id = ? and type = 'WEBHOOK_DELIVERY'", uid).Order("id").Find(&js).Error; err != nil {
+			t.Fatal(err)
+		}
+		out := map[uint64][]string{}
+		for _, j := range js {
+			var p struct {
+				SubscriptionID uint64          `json:"subscription_id"`
+				EventType      string          `json:"event_type"`
+				Body           json.RawMessage `json:"body"`
+			}
+			if err := json.Unmarshal(j.Payload, &p); err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Body) == 0 {
+				t.Fatalf("job %d has no body", j.ID)
+			}
+			out[p.SubscriptionID] = append(out[p.SubscriptionID], p.EventType)
+		}
+		return out
+	}
+
+	memoID, err := s.CreateMemo(ctx, uid, CreateMemoInput{Content: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AppendEvent(ctx, AppendEventInput{MemoID: memoID, UserID: uid, Type: "ARCHIVED"}); err != nil {
+		t.Fatal(err)
+	}
+	// rejected, so nothing committed and nothing to deliver
+	if _, err := s.AppendEvent(ctx, AppendEventInput{MemoID: memoID, UserID: uid, Type: "ARCHIVED"}); err == nil {
+		t.Fatal("second ARCHIVED accepted")
+	}
+
+	got := deliveries()
+	if want := []string{"CREATED", "ARCHIVED"}; len(got[all.ID]) != 2 || got[all.ID][0] != want[0] || got[all.ID][1] != want[1] {
+		t.Fatalf("all: %v", got[all.ID])
+	}
+	if len(got[archives.ID]) != 1 || got[archives.ID][0] != "ARCHIVED" {
+		t.Fatalf("archived only: %v", got[archives.ID])
+	}
+	if len(got[off.ID]) != 0 {
+		t.Fatalf("inactive: %v", got[off.ID])
+	}
+}
@@ -0,0 +1,257 @@
+package memo
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DetectedReminder is a date/time expression found in memo content.
+type DetectedReminder struct {
+	Phrase string    // as written, e.g. "besok jam 9"
+	At     time.Time // resolved in the user's zone
+}
+
+const weekdaysRe = `monday|tuesday|wednesday|thursday|friday|saturday|sunday|senin|selasa|rabu|kamis|jum'?at|sabtu|minggu`
+
+var (
+	// "in 2 hours", "dalam 30 menit", "2 jam lagi"
+	relRe = regexp.MustCompile(`(?i)\b(?:(?:in|dalam)\s+(\d{1,3})\s*(minutes?|mins?|hours?|hrs?|days?|weeks?|menit|jam|hari|minggu)(?:\s+lagi)?|(\d{1,3})\s*(menit|jam|hari|minggu)\s+lagi)\b`)
+
+	// "tomorrow", "next friday", "besok pagi", "senin depan", "lusa",
+	// "minggu depan" (next week; "hari minggu depan" is a Sunday)
+	dayRe = regexp.MustCompile(`(?i)\b(?:(day after tomorrow|tomorrow|today|tonight|next week|minggu depan|besok lusa|lusa|besok|hari ini|nanti malam|malam ini)|(?:(?:next|on|this)\s+|hari\s+)?(` + weekdaysRe + `)(?:\s+depan)?)(?:\s+(morning|afternoon|evening|night|pagi|siang|sore|malam))?\b`)
+
+	// "at 3pm", "3:30 pm", "at 15:00", "noon", "jam 9", "pukul 14.30", "jam 7 malam"
+	timeRe = regexp.MustCompile(`(?i)(?:\b(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)\b|\bat\s+(\d{1,2})(?::(\d{2}))?\b|\b(noon|midnight)\b|\b(?:jam|pukul|pkl\.?)\s*(\d{1,2})(?:[:.](\d{2}))?(?:\s+(pagi|siang|sore|malam))?\b)`)
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"minggu": time.Sunday, "senin": time.Monday, "selasa": time.Tuesday, "rabu": time.Wednesday,
+	"kamis": time.Thursday, "jumat": time.Friday, "jum'at": time.Friday, "sabtu": time.Saturday,
+}
+
+// partOfDay is the default hour for "morning", "sore", ...
+var partOfDay = map[string]int{
+	"morning": 9, "afternoon": 14, "evening": 18, "night": 20,
+	"pagi": 9, "siang": 12, "sore": 16, "malam": 19,
+}
+
+// ExtractReminder finds the first date/time expression in content
+// (English or Indonesian) and resolves it relative to now in loc. A day
+// without a time defaults to 09:00; a time without a day is the next such
+// time. A bare "at 5" only counts next to a day ("tomorrow at 5"), so
+// "look at 5 items" is not a reminder. ok is false when nothing is found
+// or it resolves to the past.
+func ExtractReminder(content string, now time.Time, loc *time.Location) (DetectedReminder, bool) {
+	d, ok := extract(content, now, loc)
+	if !ok || !d.At.After(now) {
+		return DetectedReminder{}, false
+	}
+	return d, true
+}
+
+// ReminderPhrase is the date/time phrase ExtractReminder would read from
+// content, whether or not it is still ahead; "" when there is none.
+func ReminderPhrase(content string) string {
+	d, _ := extract(content, time.Now(), time.UTC)
+	return d.Phrase
+}
+
+// extract is ExtractReminder without dropping past times.
+func extract(content string, now time.Time, loc *time.Location) (DetectedReminder, bool) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+
+	if m := relRe.FindStringSubmatchIndex(content); m != nil {
+		return relative(content, m, now)
+	}
+
+	dm := dayRe.FindStringSubmatchIndex(content)
+	tm := firstTime(content, dm)
+	// a time only belongs to a day when they are next to each other
+	if dm != nil && tm != nil && !adjacent(content, dm, tm) {
+		if tm[0] < dm[0] {
+			dm = nil
+		} else {
+			tm = nil
+		}
+	}
+	if dm == nil && tm == nil {
+		return DetectedReminder{}, false
+	}
+
+	y, mo, d := now.Date()
+	day := time.Date(y, mo, d, 0, 0, 0, 0, loc)
+	hour, minute := 9, 0
+	dayGiven := dm != nil
+
+	if dm != nil {
+		word := strings.ToLower(group(content, dm, 1))
+		switch word {
+		case "today", "hari ini":
+		case "tonight", "nanti malam", "malam ini":
+			hour = 20
+		case "tomorrow", "besok":
+			day = day.AddDate(0, 0, 1)
+		case "day after tomorrow", "lusa", "besok lusa":
+			day = day.AddDate(0, 0, 2)
+		case "next week", "minggu depan":
+			day = day.AddDate(0, 0, 7)
+		default:
+			wd := weekdayNames[strings.ToLower(group(content, dm, 2))]
+			diff := (int(wd) - int(day.Weekday()) + 7) % 7
+			if diff == 0 {
+				diff = 7
+			}
+			day = day.AddDate(0, 0, diff)
+		}
+		if pod := strings.ToLower(group(content, dm, 3)); pod != "" {
+			hour = partOfDay[pod]
+		}
+	}
+
+	if tm != nil {
+		// "tonight at 8", "besok malam jam 12": the day names the night
+		h, m, ok := clock(content, tm, hour >= 18)
+		if !ok {
+			return DetectedReminder{}, false
+		}
+		hour, minute = h, m
+	}
+
+	at := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+	if !dayGiven && !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+
+	start, end := span(dm, tm)
+	return DetectedReminder{Phrase: content[start:end], At: at}, true
+}
+
+// firstTime is the first timeRe match in content, skipping a bare "at N"
+// that is not next to the day match dm.
+func firstTime(content string, dm []int) []int {
+	for _, m := range timeRe.FindAllStringSubmatchIndex(content, -1) {
+		if group(content, m, 4) != "" && group(content, m, 5) == "" && (dm == nil || !adjacent(content, dm, m)) {
+			continue
+		}
+		return m
+	}
+	return nil
+}
+
+func relative(content string, m []int, now time.Time) (DetectedReminder, bool) {
+	num, unit := group(content, m, 1), group(content, m, 2)
+	if num == "" {
+		num, unit = group(content, m, 3), group(content, m, 4)
+	}
+	n, _ := strconv.Atoi(num)
+	if n <= 0 {
+		return DetectedReminder{}, false
+	}
+
+	var at time.Time
+	switch u := strings.ToLower(unit); {
+	case strings.HasPrefix(u, "min"), u == "menit":
+		at = now.Add(time.Duration(n) * time.Minute)
+	case strings.HasPrefix(u, "day"), u == "hari":
+		at = now.AddDate(0, 0, n)
+	case strings.HasPrefix(u, "h"), u == "jam":
+		at = now.Add(time.Duration(n) * time.Hour)
+	default: // weeks, minggu
+		at = now.AddDate(0, 0, 7*n)
+	}
+	return DetectedReminder{Phrase: content[m[0]:m[1]], At: at}, true
+}
+
+// clock reads hour and minute from a timeRe match. night reads a time
+// without am/pm or part of day as an evening one.
+func clock(content string, m []int, night bool) (int, int, bool) {
+	var hs, ms, mer string
+	switch {
+	case group(content, m, 1) != "":
+		hs, ms, mer = group(content, m, 1), group(content, m, 2), group(content, m, 3)
+	case group(content, m, 4) != "":
+		hs, ms = group(content, m, 4), group(content, m, 5)
+	case group(content, m, 6) != "":
+		if strings.EqualFold(group(content, m, 6), "noon") {
+			return 12, 0, true
+		}
+		return 0, 0, true
+	default:
+		hs, ms, mer = group(content, m, 7), group(content, m, 8), group(content, m, 9)
+	}
+
+	h, _ := strconv.Atoi(hs)
+	mi := 0
+	if ms != "" {
+		mi, _ = strconv.Atoi(ms)
+	}
+	if h > 23 || mi > 59 {
+		return 0, 0, false
+	}
+
+	switch strings.ToLower(mer) {
+	case "am", "pagi":
+		if h == 12 {
+			h = 0
+		}
+	case "pm", "sore":
+		if h < 12 {
+			h += 12
+		}
+	case "":
+		switch {
+		case night && h >= 1 && h <= 12:
+			h += 12
+		case h >= 1 && h <= 6:
+			// "at 3" means the afternoon, not 3 in the night
+			h += 12
+		}
+	case "malam":
+		// "jam 12 malam" is the midnight that ends the night; hour 24
+		// rolls over to 00:00 of the next day
+		if h >= 1 && h <= 12 {
+			h += 12
+		}
+	case "siang":
+		if h < 11 {
+			h += 12
+		}
+	}
+	return h, mi, true
+}
+
+func adjacent(content string, a, b []int) bool {
+	if a[0] > b[0] {
+		a, b = b, a
+	}
+	if a[1] > b[0] {
+		return true
+	}
+	gap := strings.TrimSpace(content[a[1]:b[0]])
+	return gap == "" || gap == ","
+}
+
+func span(a, b []int) (int, int) {
+	switch {
+	case a == nil:
+		return b[0], b[1]
+	case b == nil:
+		return a[0], a[1]
+	}
+	return min(a[0], b[0]), max(a[1], b[1])
+}
+
+func group(s string, m []int, i int) string {
+	if 2*i+1 >= len(m) || m[2*i] < 0 {
+		return ""
+	}
+	return s[m[2*i]:m[2*i+1]]
+}
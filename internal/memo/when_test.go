@@ -0,0 +1,60 @@
+package memo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtractReminder(t *testing.T) {
+	wib := time.FixedZone("WIB", 7*3600)
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, wib) // a Wednesday
+	at := func(d, h, m int) time.Time { return time.Date(2026, 10, d, h, m, 0, 0, wib) }
+
+	tests := []struct {
+		content string
+		phrase  string // "" = nothing found
+		at      time.Time
+	}{
+		// English
+		{"call mom tomorrow at 3pm", "tomorrow at 3pm", at(15, 15, 0)},
+		{"tomorrow at 5", "tomorrow at 5", at(15, 17, 0)},
+		{"standup at 17:00", "at 17:00", at(14, 17, 0)},
+		{"lunch at noon", "noon", at(14, 12, 0)},
+		{"next friday", "next friday", at(16, 9, 0)},
+		{"friday evening", "friday evening", at(16, 18, 0)},
+		{"tonight at 8", "tonight at 8", at(14, 20, 0)},
+		{"next week", "next week", at(21, 9, 0)},
+		{"in 2 hours", "in 2 hours", at(14, 12, 0)},
+		{"look at 5 items", "", time.Time{}},
+		{"look at 5 items at 3pm", "at 3pm", at(14, 15, 0)},
+		{"nothing here", "", time.Time{}},
+
+		// Indonesian
+		{"beli susu besok jam 9", "besok jam 9", at(15, 9, 0)},
+		{"rapat senin depan", "senin depan", at(19, 9, 0)},
+		{"review minggu depan", "minggu depan", at(21, 9, 0)},
+		{"ibadah hari minggu depan", "hari minggu depan", at(18, 9, 0)},
+		{"lusa", "lusa", at(16, 9, 0)},
+		{"jam 7 malam", "jam 7 malam", at(14, 19, 0)},
+		{"tidur jam 12 malam", "jam 12 malam", at(15, 0, 0)},
+		{"besok jam 12 malam", "besok jam 12 malam", at(16, 0, 0)},
+		{"nanti malam jam 9", "nanti malam jam 9", at(14, 21, 0)},
+		{"pukul 14.30", "pukul 14.30", at(14, 14, 30)},
+		{"jam 8 pagi", "jam 8 pagi", at(15, 8, 0)}, // already past today
+		{"30 menit lagi", "30 menit lagi", at(14, 10, 30)},
+		{"dalam 3 hari", "dalam 3 hari", at(17, 10, 0)},
+		{"hari ini jam 8 pagi", "", time.Time{}}, // in the past
+	}
+	for _, tt := range tests {
+		got, ok := ExtractReminder(tt.content, now, wib)
+		if tt.phrase == "" {
+			if ok {
+				t.Errorf("%q: got %q at %v, want nothing", tt.content, got.Phrase, got.At)
+			}
+			continue
+		}
+		if !ok || got.Phrase != tt.phrase || !got.At.Equal(tt.at) {
+			t.Errorf("%q: got (%q, %v, %v), want (%q, %v)", tt.content, got.Phrase, got.At, ok, tt.phrase, tt.at)
+		}
+	}
+}
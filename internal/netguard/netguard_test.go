@@ -0,0 +1,51 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestBlocked(t *testing.T) {
+	for ip, want := range map[string]bool{
+		"127.0.0.1":        true,
+		"::1":              true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.1.1":      true,
+		"169.254.169.254":  true,
+		"0.0.0.0":          true,
+		"::":               true,
+		"fe80::1":          true,
+		"fd00::1":          true,
+		"::ffff:10.0.0.1":  true,
+		"100.64.0.1":       true,
+		"100.127.255.254":  true,
+		"224.0.0.1":        true,
+		"ff02::1":          true,
+		"64:ff9b::a00:1":   true, // NAT64 of 10.0.0.1
+		"64:ff9b::808:808": true,
+		"64:ff9b:1::1":     true,
+		"100.128.0.1":      false,
+		"64:ff9c::1":       false,
+		"8.8.8.8":          false,
+		"2606:4700::1111":  false,
+	} {
+		if got := Blocked(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("Blocked(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second).Get(srv.URL)
+	if !errors.Is(err, ErrBlocked) {
+		t.Fatalf("err = %v, want ErrBlocked", err)
+	}
+}
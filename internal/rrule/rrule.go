@@ -0,0 +1,349 @@
+// Package rrule implements the subset of RFC 5545 recurrence rules Tell
+// supports: FREQ=DAILY|WEEKLY|MONTHLY with INTERVAL, BYDAY, COUNT and UNTIL.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalid = errors.New("invalid rrule")
+
+// WeekdayNum is a BYDAY entry. N is the ordinal within the month for
+// MONTHLY rules (1 = first, -1 = last); 0 means every such weekday.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+type Rule struct {
+	Freq     string // DAILY / WEEKLY / MONTHLY
+	Interval int
+	ByDay    []WeekdayNum
+	Count    int // 0 = unbounded
+	Until    *time.Time
+
+	// UntilFloating marks an UNTIL written without a zone (a local
+	// date-time or a date): its wall clock is read in DTSTART's zone.
+	UntilFloating bool
+}
+
+var dayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parse reads "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10". A leading "RRULE:" is allowed.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := Rule{Interval: 1}
+	if s == "" {
+		return r, fmt.Errorf("%w: empty", ErrInvalid)
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, fmt.Errorf("%w: %q", ErrInvalid, part)
+		}
+		k, v = strings.ToUpper(strings.TrimSpace(k)), strings.TrimSpace(v)
+
+		switch k {
+		case "FREQ":
+			v = strings.ToUpper(v)
+			if v != "DAILY" && v != "WEEKLY" && v != "MONTHLY" {
+				return r, fmt.Errorf("%w: unsupported FREQ %s", ErrInvalid, v)
+			}
+			r.Freq = v
+		case "INTERVAL":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("%w: INTERVAL %s", ErrInvalid, v)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("%w: COUNT %s", ErrInvalid, v)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, floating, err := parseUntil(v)
+			if err != nil {
+				return r, fmt.Errorf("%w: UNTIL %s", ErrInvalid, v)
+			}
+			r.Until, r.UntilFloating = &t, floating
+		case "BYDAY":
+			for _, d := range strings.Split(v, ",") {
+				wn, err := parseWeekdayNum(strings.ToUpper(strings.TrimSpace(d)))
+				if err != nil {
+					return r, err
+				}
+				r.ByDay = append(r.ByDay, wn)
+			}
+		case "WKST":
+			// weeks always start on Monday here
+		default:
+			return r, fmt.Errorf("%w: unsupported %s", ErrInvalid, k)
+		}
+	}
+
+	if r.Freq == "" {
+		return r, fmt.Errorf("%w: FREQ required", ErrInvalid)
+	}
+	if r.Count > 0 && r.Until != nil {
+		return r, fmt.Errorf("%w: COUNT and UNTIL are exclusive", ErrInvalid)
+	}
+	for _, wn := range r.ByDay {
+		if wn.N != 0 && r.Freq != "MONTHLY" {
+			return r, fmt.Errorf("%w: ordinal BYDAY needs FREQ=MONTHLY", ErrInvalid)
+		}
+	}
+	return r, nil
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("%w: BYDAY %s", ErrInvalid, s)
+	}
+	day, ok := dayCodes[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("%w: BYDAY %s", ErrInvalid, s)
+	}
+	wn := WeekdayNum{Day: day}
+	if num := s[:len(s)-2]; num != "" {
+		n, err := strconv.Atoi(num)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("%w: BYDAY %s", ErrInvalid, s)
+		}
+		wn.N = n
+	}
+	return wn, nil
+}
+
+// parseUntil reads an UNTIL value. floating is true for the forms without
+// a zone, whose wall clock is returned as if it were UTC.
+func parseUntil(v string) (t time.Time, floating bool, err error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102", time.RFC3339} {
+		if t, err := time.Parse(layout, v); err == nil {
+			if layout == "20060102" {
+				// a date UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, layout == "20060102T150405" || layout == "20060102", nil
+		}
+	}
+	return time.Time{}, false, ErrInvalid
+}
+
+// until is the UNTIL bound for a series anchored at start, nil if none.
+func (r Rule) until(start time.Time) *time.Time {
+	if r.Until == nil || !r.UntilFloating {
+		return r.Until
+	}
+	u := r.Until
+	t := time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(), u.Second(), 0, start.Location())
+	return &t
+}
+
+// String renders the rule in canonical RFC 5545 form.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, wn := range r.ByDay {
+			code := ""
+			if wn.N != 0 {
+				code = strconv.Itoa(wn.N)
+			}
+			for c, d := range dayCodes {
+				if d == wn.Day {
+					code += c
+				}
+			}
+			codes = append(codes, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	switch {
+	case r.Until != nil && r.UntilFloating:
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+	case r.Until != nil:
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// maxSteps bounds the search for pathological rules (e.g. BYDAY=5FR with a
+// long INTERVAL).
+const maxSteps = 100_000
+
+// Next returns the first occurrence strictly after `after` of the rule
+// anchored at start (DTSTART, always the first occurrence). ok is false
+// once COUNT or UNTIL is exhausted. Wall-clock time of start is kept in its
+// location across DST changes.
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	n := 0
+	var found time.Time
+	ok := false
+	until := r.until(start)
+	r.each(start, func(t time.Time) bool {
+		n++
+		if r.Count > 0 && n > r.Count {
+			return false
+		}
+		if until != nil && t.After(*until) {
+			return false
+		}
+		if t.After(after) {
+			found, ok = t, true
+			return false
+		}
+		return true
+	})
+	return found, ok
+}
+
+// Between returns occurrences in [from, to), at most limit.
+func (r Rule) Between(start, from, to time.Time, limit int) []time.Time {
+	var out []time.Time
+	n := 0
+	until := r.until(start)
+	r.each(start, func(t time.Time) bool {
+		n++
+		if (r.Count > 0 && n > r.Count) || (until != nil && t.After(*until)) || !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) {
+			out = append(out, t)
+		}
+		return len(out) < limit
+	})
+	return out
+}
+
+// each yields occurrences in order, starting with start, until fn returns false.
+func (r Rule) each(start time.Time, fn func(time.Time) bool) {
+	if !fn(start) {
+		return
+	}
+
+	loc := start.Location()
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, start.Nanosecond(), loc)
+	}
+	interval := max(r.Interval, 1)
+
+	emit := func(t time.Time) bool {
+		if !t.After(start) {
+			return true
+		}
+		return fn(t)
+	}
+
+	for step := 0; step < maxSteps; step++ {
+		switch r.Freq {
+		case "DAILY":
+			t := at(y, m, d+step*interval)
+			if len(r.ByDay) > 0 && !r.hasDay(t.Weekday()) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+
+		case "WEEKLY":
+			// week containing start, Monday-based
+			offset := (int(start.Weekday()) + 6) % 7
+			monday := at(y, m, d-offset+step*7*interval)
+			days := r.ByDay
+			if len(days) == 0 {
+				days = []WeekdayNum{{Day: start.Weekday()}}
+			}
+			for i := 0; i < 7; i++ {
+				t := monday.AddDate(0, 0, i)
+				t = at(t.Year(), t.Month(), t.Day())
+				if !containsDay(days, t.Weekday()) {
+					continue
+				}
+				if !emit(t) {
+					return
+				}
+			}
+
+		case "MONTHLY":
+			first := time.Date(y, m+time.Month(step*interval), 1, 0, 0, 0, 0, loc)
+			for _, t := range r.monthDays(first, d, at) {
+				if !emit(t) {
+					return
+				}
+			}
+
+		default:
+			return
+		}
+	}
+}
+
+// monthDays lists the occurrences within the month starting at first.
+func (r Rule) monthDays(first time.Time, startDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	y, m := first.Year(), first.Month()
+	daysIn := time.Date(y, m+1, 0, 0, 0, 0, 0, first.Location()).Day()
+
+	if len(r.ByDay) == 0 {
+		if startDay > daysIn {
+			return nil // e.g. the 31st in a 30-day month is skipped
+		}
+		return []time.Time{at(y, m, startDay)}
+	}
+
+	hit := make([]bool, daysIn+1)
+	for _, wn := range r.ByDay {
+		var days []int
+		for day := 1; day <= daysIn; day++ {
+			if time.Date(y, m, day, 0, 0, 0, 0, first.Location()).Weekday() == wn.Day {
+				days = append(days, day)
+			}
+		}
+		switch {
+		case wn.N == 0:
+			for _, day := range days {
+				hit[day] = true
+			}
+		case wn.N > 0 && wn.N <= len(days):
+			hit[days[wn.N-1]] = true
+		case wn.N < 0 && -wn.N <= len(days):
+			hit[days[len(days)+wn.N]] = true
+		}
+	}
+
+	var out []time.Time
+	for day := 1; day <= daysIn; day++ {
+		if hit[day] {
+			out = append(out, at(y, m, day))
+		}
+	}
+	return out
+}
+
+func (r Rule) hasDay(d time.Weekday) bool { return containsDay(r.ByDay, d) }
+
+func containsDay(days []WeekdayNum, d time.Weekday) bool {
+	for _, wn := range days {
+		if wn.Day == d {
+			return true
+		}
+	}
+	return false
+}
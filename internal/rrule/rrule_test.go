@@ -0,0 +1,153 @@
+package rrule
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseString(t *testing.T) {
+	tests := []struct {
+		in, want string // want "" = invalid
+	}{
+		{"RRULE:freq=weekly;byday=mo,we;count=10", "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;INTERVAL=1", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"FREQ=MONTHLY;BYDAY=2MO,-2TH", "FREQ=MONTHLY;BYDAY=2MO,-2TH"},
+		{"FREQ=DAILY;INTERVAL=3;UNTIL=20261231T235959Z", "FREQ=DAILY;INTERVAL=3;UNTIL=20261231T235959Z"},
+		{"FREQ=DAILY;UNTIL=2026-12-31T10:00:00+07:00", "FREQ=DAILY;UNTIL=20261231T030000Z"},
+		{"FREQ=DAILY;UNTIL=20261231T090000", "FREQ=DAILY;UNTIL=20261231T090000"},
+		{"FREQ=DAILY;UNTIL=20261231", "FREQ=DAILY;UNTIL=20261231T235959"},
+		{"FREQ=WEEKLY;WKST=SU", "FREQ=WEEKLY"},
+
+		{"", ""},
+		{"INTERVAL=2", ""},
+		{"FREQ=YEARLY", ""},
+		{"FREQ=DAILY;COUNT=0", ""},
+		{"FREQ=DAILY;INTERVAL=0", ""},
+		{"FREQ=DAILY;COUNT=2;UNTIL=20260101", ""},
+		{"FREQ=WEEKLY;BYDAY=1MO", ""},
+		{"FREQ=MONTHLY;BYDAY=6MO", ""},
+		{"FREQ=MONTHLY;BYDAY=XX", ""},
+		{"FREQ=DAILY;UNTIL=tomorrow", ""},
+		{"FREQ=DAILY;BYSETPOS=1", ""},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Parse(%q) = %v, %v; want ErrInvalid", tt.in, r, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		again, err := Parse(r.String())
+		if err != nil || again.String() != tt.want {
+			t.Errorf("round trip of %q: %q, %v", tt.want, again.String(), err)
+		}
+	}
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestOccurrences(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	jkt := mustLoad(t, "Asia/Jakarta")
+	utc := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, time.UTC) }
+	in := func(loc *time.Location, y int, m time.Month, d, h int) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		{"daily count", "FREQ=DAILY;COUNT=3", utc(2026, 1, 1, 9),
+			[]time.Time{utc(2026, 1, 1, 9), utc(2026, 1, 2, 9), utc(2026, 1, 3, 9)}},
+		{"daily byday", "FREQ=DAILY;BYDAY=SA,SU;COUNT=3", utc(2026, 1, 3, 9),
+			[]time.Time{utc(2026, 1, 3, 9), utc(2026, 1, 4, 9), utc(2026, 1, 10, 9)}},
+		{"weekly interval 2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5", utc(2026, 1, 5, 9),
+			[]time.Time{utc(2026, 1, 5, 9), utc(2026, 1, 8, 9), utc(2026, 1, 19, 9), utc(2026, 1, 22, 9), utc(2026, 2, 2, 9)}},
+		{"monthly skips the 31st", "FREQ=MONTHLY;COUNT=4", utc(2026, 1, 31, 9),
+			[]time.Time{utc(2026, 1, 31, 9), utc(2026, 3, 31, 9), utc(2026, 5, 31, 9), utc(2026, 7, 31, 9)}},
+		{"monthly second monday", "FREQ=MONTHLY;BYDAY=2MO;COUNT=3", utc(2026, 1, 12, 9),
+			[]time.Time{utc(2026, 1, 12, 9), utc(2026, 2, 9, 9), utc(2026, 3, 9, 9)}},
+		{"monthly last friday", "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", utc(2026, 1, 30, 9),
+			[]time.Time{utc(2026, 1, 30, 9), utc(2026, 2, 27, 9), utc(2026, 3, 27, 9)}},
+		{"until utc", "FREQ=DAILY;UNTIL=20260103T010000Z", in(ny, 2026, 1, 1, 20),
+			[]time.Time{in(ny, 2026, 1, 1, 20), in(ny, 2026, 1, 2, 20)}},
+		// floating and date UNTIL are read in DTSTART's zone, west and east of UTC
+		{"until floating west", "FREQ=DAILY;UNTIL=20260103T200000", in(ny, 2026, 1, 1, 20),
+			[]time.Time{in(ny, 2026, 1, 1, 20), in(ny, 2026, 1, 2, 20), in(ny, 2026, 1, 3, 20)}},
+		{"until date west", "FREQ=DAILY;UNTIL=20260103", in(ny, 2026, 1, 1, 20),
+			[]time.Time{in(ny, 2026, 1, 1, 20), in(ny, 2026, 1, 2, 20), in(ny, 2026, 1, 3, 20)}},
+		{"until date east", "FREQ=DAILY;UNTIL=20260102", in(jkt, 2026, 1, 1, 5),
+			[]time.Time{in(jkt, 2026, 1, 1, 5), in(jkt, 2026, 1, 2, 5)}},
+		// 2026-03-08 is the US spring-forward day: 09:00 stays 09:00
+		{"dst keeps wall clock", "FREQ=DAILY;COUNT=3", in(ny, 2026, 3, 7, 9),
+			[]time.Time{in(ny, 2026, 3, 7, 9), in(ny, 2026, 3, 8, 9), in(ny, 2026, 3, 9, 9)}},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := r.Between(tt.start, tt.start, tt.start.AddDate(2, 0, 0), 100)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(tt.want[i]) {
+				t.Errorf("%s: occurrence %d = %v, want %v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+
+		// Next walks the same series and stops after the last one
+		at := tt.start
+		for i := 1; i < len(tt.want); i++ {
+			next, ok := r.Next(tt.start, at)
+			if !ok || !next.Equal(tt.want[i]) {
+				t.Errorf("%s: Next after %v = %v, %v; want %v", tt.name, at, next, ok, tt.want[i])
+				break
+			}
+			at = next
+		}
+		if next, ok := r.Next(tt.start, tt.want[len(tt.want)-1]); ok {
+			t.Errorf("%s: Next after the last occurrence = %v", tt.name, next)
+		}
+	}
+
+	// the DST day is 23 hours long
+	r, _ := Parse("FREQ=DAILY")
+	a := in(ny, 2026, 3, 7, 9)
+	b, _ := r.Next(a, a)
+	if d := b.Sub(a); d != 23*time.Hour {
+		t.Errorf("across spring forward: %v apart, want 23h", d)
+	}
+}
+
+func TestBetweenLimitAndWindow(t *testing.T) {
+	r, _ := Parse("FREQ=DAILY")
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	got := r.Between(start, start.AddDate(0, 0, 10), start.AddDate(0, 1, 0), 3)
+	want := []time.Time{start.AddDate(0, 0, 10), start.AddDate(0, 0, 11), start.AddDate(0, 0, 12)}
+	if len(got) != 3 || !got[0].Equal(want[0]) || !got[2].Equal(want[2]) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Subscription delivers the user's memo events to URL. Empty EventTypes
+// means every type.
+type Subscription struct {
+	ID         uint64         `gorm:"primaryKey"`
+	UserID     uint64         `gorm:"index;not null"`
+	URL        string         `gorm:"type:text;not null"`
+	Secret     string         `gorm:"type:text;not null"`
+	EventTypes pq.StringArray `gorm:"type:text[];not null;default:'{}'"`
+	Active     bool           `gorm:"not null;default:true"`
+	CreatedAt  time.Time      `gorm:"not null;default:now()"`
+}
+
+// Matches reports whether events of typ go to this subscription.
+func (s Subscription) Matches(typ string) bool {
+	if !s.Active {
+		return false
+	}
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one HTTP attempt of a WEBHOOK_DELIVERY job.
+type Delivery struct {
+	ID             uint64    `gorm:"primaryKey"`
+	SubscriptionID uint64    `gorm:"index;not null"`
+	UserID         uint64    `gorm:"index;not null"`
+	JobID          uint64    `gorm:"index;not null"`
+	EventID        uint64    `gorm:"not null"`
+	Attempt        int       `gorm:"not null"`
+	StatusCode     int       `gorm:"not null;default:0"` // 0 = no response
+	Error          *string   `gorm:"type:text"`
+	DurationMs     int64     `gorm:"not null;default:0"`
+	CreatedAt      time.Time `gorm:"not null;default:now()"`
+}
@@ -0,0 +1,25 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Sign returns the X-Tell-Signature value for body sent at ts (unix
+// seconds): "sha256=" + hex(HMAC-SHA256(secret, "<ts>.<body>")).
+// Receivers should recompute it and reject stale timestamps.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether sig is Sign(secret, ts, body), in constant time.
+// Checking that ts is recent is left to the caller.
+func Verify(secret string, ts int64, body []byte, sig string) bool {
+	return hmac.Equal([]byte(sig), []byte(Sign(secret, ts, body)))
+}
@@ -0,0 +1,50 @@
+package webhook
+
+import "testing"
+
+func TestSignVerify(t *testing.T) {
+	const (
+		secret = "whsec_test"
+		ts     = int64(1767225600)
+		want   = "sha256=c288d7ec0b1747de22e35fbdbabba9772c8e0d64b7db67e546bc92b86c005ab8"
+	)
+	body := []byte(`{"id":1}`)
+
+	if got := Sign(secret, ts, body); got != want {
+		t.Fatalf("Sign = %s, want %s", got, want)
+	}
+	if !Verify(secret, ts, body, want) {
+		t.Fatal("Verify rejected a good signature")
+	}
+	for name, ok := range map[string]bool{
+		"other secret": Verify("whsec_other", ts, body, want),
+		"other ts":     Verify(secret, ts+1, body, want),
+		"other body":   Verify(secret, ts, []byte(`{"id":2}`), want),
+		"bare hex":     Verify(secret, ts, body, want[len("sha256="):]),
+		"empty":        Verify(secret, ts, body, ""),
+	} {
+		if ok {
+			t.Errorf("%s: verified", name)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	all := Subscription{Active: true}
+	some := Subscription{Active: true, EventTypes: []string{"CREATED", "ARCHIVED"}}
+	off := Subscription{Active: false}
+	for _, tt := range []struct {
+		s    Subscription
+		typ  string
+		want bool
+	}{
+		{all, "UPDATED", true},
+		{some, "ARCHIVED", true},
+		{some, "UPDATED", false},
+		{off, "CREATED", false},
+	} {
+		if got := tt.s.Matches(tt.typ); got != tt.want {
+			t.Errorf("%v.Matches(%s) = %v", tt.s.EventTypes, tt.typ, got)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tell/internal/netguard"
+)
+
+// ErrGone means the push service no longer knows the subscription
+// (404/410); it should be deleted.
+var ErrGone = errors.New("webpush: subscription gone")
+
+// StatusError is any other non-2xx answer from the push service.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webpush: http %d: %s", e.Code, e.Body)
+}
+
+// Client delivers encrypted messages to push service endpoints.
+type Client struct {
+	VAPID *VAPID
+	HTTP  *http.Client // nil = netguard client, 10s timeout
+	TTL   time.Duration
+}
+
+// Send encrypts payload for sub and POSTs it to sub.Endpoint.
+func (c *Client) Send(ctx context.Context, sub Subscription, payload []byte) error {
+	body, err := Encrypt(sub.P256dh, sub.Auth, payload)
+	if err != nil {
+		return err
+	}
+	auth, err := c.VAPID.Authorization(sub.Endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", "high")
+	req.Header.Set("Authorization", auth)
+
+	hc := c.HTTP
+	if hc == nil {
+		hc = defaultHTTP
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	}
+	return &StatusError{Code: resp.StatusCode, Body: string(msg)}
+}
+
+// defaultHTTP refuses internal addresses: endpoints are user input.
+var defaultHTTP = netguard.NewClient(10 * time.Second)
@@ -0,0 +1,93 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testSubscription is a browser stand-in: its keys and a Subscription
+// pointing at endpoint.
+func testSubscription(t *testing.T, endpoint string) (Subscription, *ecdh.PrivateKey, []byte) {
+	t.Helper()
+	ua, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return Subscription{
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(ua.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(auth),
+	}, ua, auth
+}
+
+func TestClientSend(t *testing.T) {
+	pub, priv, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vapid, err := NewVAPID(priv, "mailto:ops@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := http.StatusCreated
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	// the default client refuses loopback, as it should
+	c := &Client{VAPID: vapid, HTTP: srv.Client()}
+	sub, ua, auth := testSubscription(t, srv.URL+"/push/abc")
+	msg := []byte(`{"kind":"reminder","title":"hi"}`)
+
+	if err := c.Send(context.Background(), sub, msg); err != nil {
+		t.Fatalf("201: %v", err)
+	}
+	if got.Header.Get("Content-Encoding") != "aes128gcm" || got.Header.Get("TTL") == "" {
+		t.Fatalf("headers %v", got.Header)
+	}
+	if a := got.Header.Get("Authorization"); !strings.HasPrefix(a, "vapid t=") || !strings.HasSuffix(a, ", k="+pub) {
+		t.Fatalf("authorization %q", a)
+	}
+	plain, err := Decrypt(ua.Bytes(), auth, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, msg) {
+		t.Fatalf("decrypted %q", plain)
+	}
+
+	for _, code := range []int{http.StatusNotFound, http.StatusGone} {
+		status = code
+		if err := c.Send(context.Background(), sub, msg); !errors.Is(err, ErrGone) {
+			t.Fatalf("%d: %v", code, err)
+		}
+	}
+
+	status = http.StatusTooManyRequests
+	var se *StatusError
+	if err := c.Send(context.Background(), sub, msg); !errors.As(err, &se) || se.Code != status {
+		t.Fatalf("429: %v", err)
+	}
+
+	// without an override, loopback endpoints are refused
+	if err := (&Client{VAPID: vapid}).Send(context.Background(), sub, msg); err == nil {
+		t.Fatal("default client reached a loopback endpoint")
+	}
+}
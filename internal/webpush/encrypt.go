@@ -0,0 +1,170 @@
+// Package webpush sends Web Push messages: payload encryption per RFC 8291
+// (aes128gcm, RFC 8188) and VAPID authentication per RFC 8292.
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// recordSize is the aes128gcm record size; messages use a single record.
+const recordSize = 4096
+
+// MaxPayload is the largest plaintext push services must accept
+// (RFC 8291 §4): 4096 bytes of body less the 86 byte header (salt, rs,
+// idlen and the 65 byte key), the 16 byte tag and the delimiter byte.
+const MaxPayload = 4096 - 86 - 16 - 1
+
+var ErrPayloadTooLarge = errors.New("webpush: payload too large")
+
+// DecodeKey accepts base64url with or without padding, which is how
+// browsers and key generators hand keys around.
+func DecodeKey(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// Encrypt encrypts plaintext for the subscription's user agent keys
+// (ua_public = p256dh, auth_secret = auth) and returns the aes128gcm body.
+func Encrypt(p256dh, auth string, plaintext []byte) ([]byte, error) {
+	uaPub, err := DecodeKey(p256dh)
+	if err != nil {
+		return nil, err
+	}
+	authSecret, err := DecodeKey(auth)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	asPriv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(uaPub, authSecret, salt, asPriv, plaintext)
+}
+
+func encrypt(uaPub, authSecret, salt []byte, asPriv *ecdh.PrivateKey, plaintext []byte) ([]byte, error) {
+	if len(plaintext) > MaxPayload {
+		return nil, ErrPayloadTooLarge
+	}
+	ua, err := ecdh.P256().NewPublicKey(uaPub)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := asPriv.ECDH(ua)
+	if err != nil {
+		return nil, err
+	}
+	asPub := asPriv.PublicKey().Bytes()
+
+	gcm, nonce, err := contentKeys(shared, authSecret, salt, uaPub, asPub)
+	if err != nil {
+		return nil, err
+	}
+
+	// single record: plaintext, then the 0x02 last-record delimiter
+	record := append(append([]byte{}, plaintext...), 0x02)
+
+	header := make([]byte, 0, 16+4+1+len(asPub))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPub)))
+	header = append(header, asPub...)
+
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// Decrypt is the user agent side of Encrypt, for stand-in push services
+// and tests. uaPriv is the raw P-256 private key behind p256dh.
+func Decrypt(uaPriv, authSecret, body []byte) ([]byte, error) {
+	if len(body) < 21 {
+		return nil, errors.New("webpush: short body")
+	}
+	salt := body[:16]
+	idLen := int(body[20])
+	if len(body) < 21+idLen {
+		return nil, errors.New("webpush: short body")
+	}
+	asPubBytes := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	priv, err := ecdh.P256().NewPrivateKey(uaPriv)
+	if err != nil {
+		return nil, err
+	}
+	asPub, err := ecdh.P256().NewPublicKey(asPubBytes)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := priv.ECDH(asPub)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, nonce, err := contentKeys(shared, authSecret, salt, priv.PublicKey().Bytes(), asPubBytes)
+	if err != nil {
+		return nil, err
+	}
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// strip padding back to the delimiter
+	i := len(record) - 1
+	for i >= 0 && record[i] == 0 {
+		i--
+	}
+	if i < 0 || record[i] != 0x02 {
+		return nil, errors.New("webpush: bad record delimiter")
+	}
+	return record[:i], nil
+}
+
+// contentKeys derives the AES-GCM key and nonce (RFC 8291 section 3.4).
+func contentKeys(shared, authSecret, salt, uaPub, asPub []byte) (cipher.AEAD, []byte, error) {
+	prkKey, err := hkdf.Extract(sha256.New, shared, authSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPub) + string(asPub)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gcm, nonce, nil
+}
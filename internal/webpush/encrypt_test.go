@@ -0,0 +1,82 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+)
+
+func mustKey(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := DecodeKey(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// RFC 8291 Appendix A.
+func TestEncryptRFC8291Vector(t *testing.T) {
+	var (
+		plaintext  = []byte("When I grow up, I want to be a watermelon")
+		asPrivRaw  = mustKey(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw")
+		uaPub      = mustKey(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4")
+		uaPrivRaw  = mustKey(t, "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94")
+		authSecret = mustKey(t, "BTBZMqHH6r4Tts7J_aSIgg")
+		salt       = mustKey(t, "DGv6ra1nlYgDCS1FRnbzlw")
+		want       = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	)
+	asPriv, err := ecdh.P256().NewPrivateKey(asPrivRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := encrypt(uaPub, authSecret, salt, asPriv, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base64.RawURLEncoding.EncodeToString(body); got != want {
+		t.Fatalf("body\n got %s\nwant %s", got, want)
+	}
+
+	got, err := Decrypt(uaPrivRaw, authSecret, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("decrypted %q", got)
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	ua, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+
+	p256dh := base64.RawURLEncoding.EncodeToString(ua.PublicKey().Bytes())
+	// browsers hand out padded base64url too
+	authStr := base64.URLEncoding.EncodeToString(auth)
+
+	for _, msg := range [][]byte{{}, []byte(`{"kind":"reminder"}`), bytes.Repeat([]byte("x"), MaxPayload)} {
+		body, err := Encrypt(p256dh, authStr, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Decrypt(ua.Bytes(), auth, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("round trip of %d bytes: got %d bytes", len(msg), len(got))
+		}
+	}
+
+	if _, err := Encrypt(p256dh, authStr, make([]byte, MaxPayload+1)); err != ErrPayloadTooLarge {
+		t.Fatalf("oversized payload: %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package webpush
+
+import "time"
+
+// Subscription is a browser push subscription (PushSubscription.toJSON()).
+// P256dh and Auth are the user agent's keys, base64url as the browser
+// sends them.
+type Subscription struct {
+	ID         uint64     `gorm:"primaryKey"`
+	UserID     uint64     `gorm:"index;not null"`
+	Endpoint   string     `gorm:"type:text;uniqueIndex;not null"`
+	P256dh     string     `gorm:"type:text;not null"`
+	Auth       string     `gorm:"type:text;not null"`
+	UserAgent  string     `gorm:"type:text;not null;default:''"`
+	CreatedAt  time.Time  `gorm:"not null;default:now()"`
+	LastUsedAt *time.Time `gorm:"type:timestamptz"`
+}
+
+func (Subscription) TableName() string { return "push_subscriptions" }
@@ -0,0 +1,81 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// VAPID is the application server key pair (RFC 8292). Subject is a
+// mailto: or https: contact for push services.
+type VAPID struct {
+	Subject   string
+	PublicKey string // base64url uncompressed P-256 point, for applicationServerKey
+
+	priv *ecdsa.PrivateKey
+}
+
+// NewVAPID loads a key pair from a base64url raw P-256 private key; the
+// public key is derived from it.
+func NewVAPID(privateKey, subject string) (*VAPID, error) {
+	raw, err := DecodeKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("vapid private key: %w", err)
+	}
+	k, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("vapid private key: %w", err)
+	}
+	pub := k.PublicKey().Bytes() // 0x04 || X || Y
+
+	priv := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}
+	return &VAPID{
+		Subject:   subject,
+		PublicKey: base64.RawURLEncoding.EncodeToString(pub),
+		priv:      priv,
+	}, nil
+}
+
+// GenerateVAPIDKeys returns a new base64url key pair.
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	k, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(k.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(k.Bytes()), nil
+}
+
+// Authorization is the header value for a push to endpoint:
+// "vapid t=<ES256 JWT>, k=<public key>".
+func (v *VAPID) Authorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(12 * time.Hour).Unix(),
+		"sub": v.Subject,
+	}
+	t, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(v.priv)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + t + ", k=" + v.PublicKey, nil
+}